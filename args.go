@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Positional argument a command accepts
+// A variadic argument must come last and collects every remaining word
+// complete, if set, returns the values offered by tab completion
+type argSpec struct {
+	name     string
+	optional bool
+	variadic bool
+	complete func(r *repl) []string
+}
+
+// Flag a command accepts as --name value, --name=value or -name value
+// Boolean flags take no value and are set by their presence
+type flagSpec struct {
+	name     string
+	usage    string
+	boolean  bool
+	defValue string
+}
+
+// Validated input passed to a command callback
+type commandArgs struct {
+	values map[string]string
+	rest   []string
+	flags  map[string]string
+}
+
+// Returns the positional argument called name, or "" if it was optional and not given
+func (a commandArgs) Arg(name string) string {
+	return a.values[name]
+}
+
+// Returns the words collected by the variadic argument
+func (a commandArgs) Rest() []string {
+	return a.rest
+}
+
+// Returns the value of the flag called name, or its default if it was not given
+func (a commandArgs) Flag(name string) string {
+	return a.flags[name]
+}
+
+// Reports whether the boolean flag called name was given
+func (a commandArgs) Bool(name string) bool {
+	return a.flags[name] == "true"
+}
+
+// Error for input that cannot be parsed or does not match a command's arguments and flags
+// The message is followed by the command's usage line when the command is known
+type usageError struct {
+	command *cliCommand
+	msg     string
+}
+
+func (e *usageError) Error() string {
+	if e.command == nil {
+		return e.msg + "\n"
+	}
+	return fmt.Sprintf("%s\nusage: %s\n", e.msg, e.command.usage())
+}
+
+// Returns the usage line built from the command's path, flags and arguments
+func (c cliCommand) usage() string {
+	name := c.path
+	if name == "" {
+		name = c.name
+	}
+	parts := []string{name}
+	for _, f := range c.flags {
+		if f.boolean {
+			parts = append(parts, fmt.Sprintf("[--%s]", f.name))
+		} else {
+			parts = append(parts, fmt.Sprintf("[--%s value]", f.name))
+		}
+	}
+	for _, a := range c.args {
+		switch {
+		case a.variadic && a.optional:
+			parts = append(parts, fmt.Sprintf("[%s...]", a.name))
+		case a.variadic:
+			parts = append(parts, fmt.Sprintf("<%s>...", a.name))
+		case a.optional:
+			parts = append(parts, fmt.Sprintf("[%s]", a.name))
+		default:
+			parts = append(parts, fmt.Sprintf("<%s>", a.name))
+		}
+	}
+	if len(c.subcommands) > 0 && len(c.args) == 0 {
+		if c.callback == nil {
+			parts = append(parts, "<subcommand>")
+		} else {
+			parts = append(parts, "[subcommand]")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Splits a line into words the way a shell does
+// Words are separated by spaces or tabs; single quotes keep everything inside literally,
+// double quotes allow \" and \\ escapes, and a backslash outside quotes escapes the next character
+// Returns a usageError for an unterminated quote or trailing backslash
+func splitArgs(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			if quote == '"' && r != '"' && r != '\\' {
+				word.WriteRune('\\')
+			}
+			word.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case quote == '"':
+			switch r {
+			case '"':
+				quote = 0
+			case '\\':
+				escaped = true
+			default:
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == '\\':
+			escaped = true
+			inWord = true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, &usageError{msg: fmt.Sprintf("unterminated %c quote", quote)}
+	}
+	if escaped {
+		return nil, &usageError{msg: "trailing backslash"}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// Matches words against the command's flags and positional arguments
+// Flags may appear anywhere; "--" ends flag parsing so later words are always positional
+// Returns a usageError for unknown flags, missing flag values and too few or too many arguments
+func parseArgs(command cliCommand, words []string) (commandArgs, error) {
+	parsed := commandArgs{
+		values: make(map[string]string),
+		flags:  make(map[string]string),
+	}
+	for _, f := range command.flags {
+		parsed.flags[f.name] = f.defValue
+	}
+	var positional []string
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		if word == "--" {
+			positional = append(positional, words[i+1:]...)
+			break
+		}
+		if len(word) < 2 || word[0] != '-' {
+			positional = append(positional, word)
+			continue
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(word, "-"), "=")
+		spec, ok := command.flag(name)
+		if !ok {
+			return parsed, &usageError{&command, fmt.Sprintf("unknown flag %s", word)}
+		}
+		switch {
+		case spec.boolean && hasValue:
+			return parsed, &usageError{&command, fmt.Sprintf("flag --%s takes no value", name)}
+		case spec.boolean:
+			value = "true"
+		case !hasValue:
+			if i+1 == len(words) {
+				return parsed, &usageError{&command, fmt.Sprintf("flag --%s needs a value", name)}
+			}
+			i++
+			value = words[i]
+		}
+		parsed.flags[name] = value
+	}
+
+	for i, spec := range command.args {
+		if i >= len(positional) {
+			if !spec.optional {
+				return parsed, &usageError{&command, fmt.Sprintf("missing <%s>", spec.name)}
+			}
+			break
+		}
+		parsed.values[spec.name] = positional[i]
+		if spec.variadic {
+			parsed.rest = positional[i:]
+			positional = positional[:i+1]
+			break
+		}
+	}
+	if len(positional) > len(command.args) {
+		return parsed, &usageError{&command, fmt.Sprintf("unexpected argument %q", positional[len(command.args)])}
+	}
+	return parsed, nil
+}
+
+// Returns the flag called name
+func (c cliCommand) flag(name string) (flagSpec, bool) {
+	for _, f := range c.flags {
+		if f.name == name {
+			return f, true
+		}
+	}
+	return flagSpec{}, false
+}
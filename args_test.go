@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	cases := []struct {
+		line string
+		want []string
+	}{
+		{"", nil},
+		{"   ", nil},
+		{"explore mt-moon", []string{"explore", "mt-moon"}},
+		{"  catch \t pikachu  ", []string{"catch", "pikachu"}},
+		{`cache export "my cache.gz"`, []string{"cache", "export", "my cache.gz"}},
+		{`cache export 'it''s'`, []string{"cache", "export", "its"}},
+		{`say 'a "b" c'`, []string{"say", `a "b" c`}},
+		{`say "a \"b\" \\ \n"`, []string{"say", `a "b" \ \n`}},
+		{`say a\ b`, []string{"say", "a b"}},
+		{`say ""`, []string{"say", ""}},
+	}
+	for _, c := range cases {
+		got, err := splitArgs(c.line)
+		if err != nil {
+			t.Errorf("splitArgs(%q) error: %v", c.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("splitArgs(%q) = %q, want %q", c.line, got, c.want)
+		}
+	}
+}
+
+func TestSplitArgsErrors(t *testing.T) {
+	for _, line := range []string{`say "open`, `say 'open`, `say trailing\`} {
+		if _, err := splitArgs(line); err == nil {
+			t.Errorf("splitArgs(%q) succeeded, want error", line)
+		}
+	}
+}
+
+var testCommand = cliCommand{
+	name: "prefetch",
+	args: []argSpec{{name: "kind"}, {name: "name", optional: true}},
+	flags: []flagSpec{
+		{name: "workers", defValue: "8"},
+		{name: "dry-run", boolean: true},
+	},
+}
+
+func TestParseArgs(t *testing.T) {
+	cases := []struct {
+		words   []string
+		values  map[string]string
+		workers string
+		dryRun  bool
+	}{
+		{[]string{"region"}, map[string]string{"kind": "region"}, "8", false},
+		{[]string{"region", "kanto"}, map[string]string{"kind": "region", "name": "kanto"}, "8", false},
+		{[]string{"--workers", "3", "region", "kanto"}, map[string]string{"kind": "region", "name": "kanto"}, "3", false},
+		{[]string{"region", "--workers=4", "kanto", "--dry-run"}, map[string]string{"kind": "region", "name": "kanto"}, "4", true},
+		{[]string{"-workers", "5", "region"}, map[string]string{"kind": "region"}, "5", false},
+		{[]string{"--", "--workers", "x"}, map[string]string{"kind": "--workers", "name": "x"}, "8", false},
+	}
+	for _, c := range cases {
+		got, err := parseArgs(testCommand, c.words)
+		if err != nil {
+			t.Errorf("parseArgs(%q) error: %v", c.words, err)
+			continue
+		}
+		if !reflect.DeepEqual(got.values, c.values) {
+			t.Errorf("parseArgs(%q) values = %v, want %v", c.words, got.values, c.values)
+		}
+		if got.Flag("workers") != c.workers {
+			t.Errorf("parseArgs(%q) workers = %q, want %q", c.words, got.Flag("workers"), c.workers)
+		}
+		if got.Bool("dry-run") != c.dryRun {
+			t.Errorf("parseArgs(%q) dry-run = %v, want %v", c.words, got.Bool("dry-run"), c.dryRun)
+		}
+	}
+}
+
+func TestParseArgsUsageErrors(t *testing.T) {
+	cases := []struct {
+		words []string
+		msg   string
+	}{
+		{nil, "missing <kind>"},
+		{[]string{"region", "kanto", "johto"}, `unexpected argument "johto"`},
+		{[]string{"--fast", "region"}, "unknown flag --fast"},
+		{[]string{"region", "--workers"}, "flag --workers needs a value"},
+		{[]string{"region", "--dry-run=yes"}, "flag --dry-run takes no value"},
+	}
+	for _, c := range cases {
+		_, err := parseArgs(testCommand, c.words)
+		var usage *usageError
+		if !errors.As(err, &usage) {
+			t.Errorf("parseArgs(%q) error = %v, want usage error", c.words, err)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), c.msg+"\n") {
+			t.Errorf("parseArgs(%q) error = %q, want prefix %q", c.words, err, c.msg)
+		}
+		if !strings.Contains(err.Error(), "usage: prefetch [--workers value] [--dry-run] <kind> [name]") {
+			t.Errorf("parseArgs(%q) error %q lacks usage line", c.words, err)
+		}
+	}
+}
+
+func TestParseArgsVariadic(t *testing.T) {
+	command := cliCommand{name: "catch", args: []argSpec{{name: "pokemon", variadic: true}}}
+	got, err := parseArgs(command, []string{"pikachu", "eevee", "mew"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Arg("pokemon") != "pikachu" || !reflect.DeepEqual(got.Rest(), []string{"pikachu", "eevee", "mew"}) {
+		t.Errorf("got %q and rest %q", got.Arg("pokemon"), got.Rest())
+	}
+	if _, err := parseArgs(command, nil); err == nil {
+		t.Error("parseArgs with no pokemon succeeded, want missing argument error")
+	}
+}
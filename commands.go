@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// A REPL command: its name, what it does, the arguments and flags it accepts
+// and the callback run with the validated input
+// A command with subcommands may leave callback nil to require one of them
+type cliCommand struct {
+	name        string
+	aliases     []string
+	category    string
+	description string
+	long        string
+	examples    []string
+	args        []argSpec
+	flags       []flagSpec
+	subcommands []cliCommand
+	callback    func(*repl, commandArgs) error
+	// Set for commands whose run is not traced, such as trace itself; subcommands inherit it
+	untraced bool
+	// Full name including any parent commands, set by register
+	path string
+}
+
+// Category used for commands registered without one
+const defaultCategory = "General"
+
+// Every REPL command, registered once at startup
+var registry = newCommandRegistry()
+
+// Commands looked up by name or alias
+// Subcommands live on their parent command rather than in the registry
+type commandRegistry struct {
+	commands map[string]*cliCommand
+	primary  []*cliCommand
+}
+
+func newCommandRegistry() *commandRegistry {
+	return &commandRegistry{commands: make(map[string]*cliCommand)}
+}
+
+// Adds the command and its aliases to the registry
+// Names are stored lowercase; panics if a name or alias is already taken, as that is a programming error
+func (r *commandRegistry) register(command cliCommand) {
+	command.setPaths("")
+	if command.category == "" {
+		command.category = defaultCategory
+	}
+	c := &command
+	for _, name := range append([]string{c.name}, c.aliases...) {
+		name = strings.ToLower(name)
+		if _, taken := r.commands[name]; taken {
+			panic(fmt.Sprintf("command %q registered twice", name))
+		}
+		r.commands[name] = c
+	}
+	r.primary = append(r.primary, c)
+}
+
+// Returns the command called name or having it as an alias, ignoring case
+func (r *commandRegistry) lookup(name string) (*cliCommand, bool) {
+	c, ok := r.commands[strings.ToLower(name)]
+	return c, ok
+}
+
+// Finds the command named by the leading words, descending into subcommands
+// Returns the command and the words left over
+func (r *commandRegistry) find(words []string) (*cliCommand, []string, error) {
+	c, ok := r.lookup(words[0])
+	if !ok {
+		return nil, nil, &usageError{msg: fmt.Sprintf("unknown command %q; type \"help\" for a list", words[0])}
+	}
+	words = words[1:]
+	for len(words) > 0 {
+		sub, ok := c.subcommand(words[0])
+		if !ok {
+			break
+		}
+		c, words = sub, words[1:]
+	}
+	return c, words, nil
+}
+
+// Finds the command to run for the leading words
+// Returns the command and the words left over for its arguments
+// Returns a usageError if the words stop at a command that needs a subcommand
+func (r *commandRegistry) resolve(words []string) (*cliCommand, []string, error) {
+	c, words, err := r.find(words)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.callback == nil {
+		if len(words) > 0 {
+			return nil, nil, &usageError{c, fmt.Sprintf("unknown subcommand %q", words[0])}
+		}
+		return nil, nil, &usageError{c, "missing subcommand"}
+	}
+	return c, words, nil
+}
+
+// Returns every registered command once, sorted by category and then name
+func (r *commandRegistry) sorted() []*cliCommand {
+	commands := append([]*cliCommand(nil), r.primary...)
+	sort.Slice(commands, func(i, j int) bool {
+		if commands[i].category != commands[j].category {
+			return commands[i].category < commands[j].category
+		}
+		return commands[i].name < commands[j].name
+	})
+	return commands
+}
+
+// Sets the full path, such as "cache export", of the command and its subcommands, which also inherit untraced
+func (c *cliCommand) setPaths(parent string) {
+	c.path = strings.TrimSpace(parent + " " + c.name)
+	for i := range c.subcommands {
+		c.subcommands[i].untraced = c.subcommands[i].untraced || c.untraced
+		c.subcommands[i].setPaths(c.path)
+	}
+}
+
+// Returns the subcommand called name or having it as an alias, ignoring case
+func (c *cliCommand) subcommand(name string) (*cliCommand, bool) {
+	for i := range c.subcommands {
+		sub := &c.subcommands[i]
+		if strings.EqualFold(sub.name, name) {
+			return sub, true
+		}
+		for _, alias := range sub.aliases {
+			if strings.EqualFold(alias, name) {
+				return sub, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Writes every command grouped by category, sorted by name within each group
+func (r *commandRegistry) writeHelp(w io.Writer) {
+	fmt.Fprintf(w, "Welcome to the CLI Pokedex!\nUsage:\n")
+	category := ""
+	for _, c := range r.sorted() {
+		if c.category != category {
+			category = c.category
+			fmt.Fprintf(w, "\n%s:\n", category)
+		}
+		fmt.Fprintf(w, "  %-10s %s\n", c.name, c.description)
+	}
+	fmt.Fprintf(w, "\nType \"help <command>\" for details on a command.\n")
+}
+
+// Writes the command's usage, aliases, long help, flags, subcommands and examples
+func (c *cliCommand) writeHelp(w io.Writer) {
+	fmt.Fprintf(w, "usage: %s\n\n%s\n", c.usage(), c.description)
+	if c.long != "" {
+		fmt.Fprintf(w, "\n%s\n", c.long)
+	}
+	if len(c.aliases) > 0 {
+		fmt.Fprintf(w, "\nAliases: %s\n", strings.Join(c.aliases, ", "))
+	}
+	if len(c.flags) > 0 {
+		fmt.Fprintf(w, "\nFlags:\n")
+		for _, f := range c.flags {
+			name := "--" + f.name
+			if !f.boolean {
+				name += " value"
+			}
+			fmt.Fprintf(w, "  %-20s %s", name, f.usage)
+			if f.defValue != "" {
+				fmt.Fprintf(w, " (default %s)", f.defValue)
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	if len(c.subcommands) > 0 {
+		fmt.Fprintf(w, "\nSubcommands:\n")
+		for _, sub := range c.subcommands {
+			fmt.Fprintf(w, "  %-20s %s\n", sub.usage(), sub.description)
+		}
+	}
+	if len(c.examples) > 0 {
+		fmt.Fprintf(w, "\nExamples:\n")
+		for _, example := range c.examples {
+			fmt.Fprintf(w, "  %s\n", example)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func noop(*repl, commandArgs) error { return nil }
+
+func testRegistry() *commandRegistry {
+	r := newCommandRegistry()
+	r.register(cliCommand{name: "map", category: "Exploring", description: "next areas", callback: noop})
+	r.register(cliCommand{name: "exit", aliases: []string{"quit"}, description: "leave", callback: noop})
+	r.register(cliCommand{name: "explore", category: "Exploring", description: "area pokemon", args: []argSpec{{name: "area"}}, callback: noop})
+	r.register(cliCommand{name: "help", description: "help", callback: noop})
+	r.register(cliCommand{
+		name:     "cache",
+		category: "Cache",
+		subcommands: []cliCommand{
+			{name: "export", description: "write bundle", args: []argSpec{{name: "file"}}, callback: noop},
+			{name: "stats", aliases: []string{"st"}, description: "counts", callback: noop},
+		},
+	})
+	return r
+}
+
+func TestRegistryResolve(t *testing.T) {
+	r := testRegistry()
+	cases := []struct {
+		words []string
+		path  string
+		rest  []string
+	}{
+		{[]string{"map"}, "map", []string{}},
+		{[]string{"QUIT"}, "exit", []string{}},
+		{[]string{"Explore", "mt-moon"}, "explore", []string{"mt-moon"}},
+		{[]string{"cache", "export", "kanto.bundle"}, "cache export", []string{"kanto.bundle"}},
+		{[]string{"cache", "ST"}, "cache stats", []string{}},
+	}
+	for _, c := range cases {
+		command, rest, err := r.resolve(c.words)
+		if err != nil {
+			t.Errorf("resolve(%q) error: %v", c.words, err)
+			continue
+		}
+		if command.path != c.path || strings.Join(rest, " ") != strings.Join(c.rest, " ") {
+			t.Errorf("resolve(%q) = %q %q, want %q %q", c.words, command.path, rest, c.path, c.rest)
+		}
+	}
+}
+
+func TestRegistryResolveErrors(t *testing.T) {
+	r := testRegistry()
+	if _, _, err := r.resolve([]string{"fly"}); err == nil || !strings.Contains(err.Error(), `unknown command "fly"`) {
+		t.Errorf("resolve(fly) error = %v, want unknown command", err)
+	}
+	var usage *usageError
+	_, _, err := r.resolve([]string{"cache"})
+	if !errors.As(err, &usage) || !strings.Contains(err.Error(), "usage: cache <subcommand>") {
+		t.Errorf("resolve(cache) error = %v, want usage error", err)
+	}
+	_, _, err = r.resolve([]string{"cache", "wipe"})
+	if !errors.As(err, &usage) || !strings.Contains(err.Error(), `unknown subcommand "wipe"`) {
+		t.Errorf("resolve(cache wipe) error = %v, want unknown subcommand", err)
+	}
+}
+
+func TestRegisterTwicePanics(t *testing.T) {
+	r := testRegistry()
+	defer func() {
+		if recover() == nil {
+			t.Error("registering an alias twice did not panic")
+		}
+	}()
+	r.register(cliCommand{name: "leave", aliases: []string{"Quit"}, callback: noop})
+}
+
+func TestHelpIsGroupedAndSorted(t *testing.T) {
+	var out bytes.Buffer
+	testRegistry().writeHelp(&out)
+	want := []string{"Cache:", "cache", "Exploring:", "explore", "map", "General:", "exit", "help"}
+	var got []string
+	for _, line := range strings.Split(out.String(), "\n") {
+		if strings.HasPrefix(line, "  ") || (strings.HasSuffix(line, ":") && line != "Usage:") {
+			got = append(got, strings.Fields(line)[0])
+		}
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("help lists %q, want %q", got, want)
+	}
+}
+
+func TestCommandHelp(t *testing.T) {
+	r := testRegistry()
+	command, _, err := r.find([]string{"cache"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	command.writeHelp(&out)
+	for _, want := range []string{"usage: cache <subcommand>", "cache export <file>", "cache stats"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("help for cache lacks %q:\n%s", want, out.String())
+		}
+	}
+}
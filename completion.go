@@ -0,0 +1,96 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// Names of caught pokemon, offered when completing inspect
+func (r *repl) caughtPokemon() []string {
+	return r.pokedex.Keys()
+}
+
+// Names and aliases of every registered command
+func commandNames() []string {
+	names := make([]string, 0, len(registry.commands))
+	for name := range registry.commands {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Completes the word before the cursor in a REPL line
+// The first word completes to command names; later words to subcommands,
+// the command's flags after "-", or the values its next argument offers
+// Quoting is not taken into account, as completed names never need it
+func (r *repl) completeLine(line string) (int, []string) {
+	start := strings.LastIndexAny(line, " \t") + 1
+	word := line[start:]
+	before := strings.Fields(line[:start])
+	if len(before) == 0 {
+		return start, matching(commandNames(), word)
+	}
+	command, rest, err := registry.find(before)
+	if err != nil {
+		return start, nil
+	}
+	// help takes a command path, so later words complete to subcommands of the named command
+	if command.name == "help" && len(rest) > 0 {
+		named, extra, err := registry.find(rest)
+		if err != nil || len(extra) > 0 {
+			return start, nil
+		}
+		var subcommands []string
+		for _, sub := range named.subcommands {
+			subcommands = append(subcommands, sub.name)
+		}
+		return start, matching(subcommands, word)
+	}
+	if strings.HasPrefix(word, "-") {
+		var flags []string
+		for _, f := range command.flags {
+			flags = append(flags, "--"+f.name)
+		}
+		return start, matching(flags, word)
+	}
+	positional := 0
+	for i := 0; i < len(rest); i++ {
+		if !strings.HasPrefix(rest[i], "-") {
+			positional++
+			continue
+		}
+		if f, ok := command.flag(strings.TrimLeft(rest[i], "-")); ok && !f.boolean && !strings.Contains(rest[i], "=") {
+			i++
+		}
+	}
+	var candidates []string
+	if positional == 0 {
+		for _, sub := range command.subcommands {
+			candidates = append(candidates, sub.name)
+		}
+	}
+	if positional < len(command.args) {
+		if complete := command.args[positional].complete; complete != nil {
+			candidates = append(candidates, complete(r)...)
+		}
+	} else if n := len(command.args); n > 0 && command.args[n-1].variadic {
+		if complete := command.args[n-1].complete; complete != nil {
+			candidates = append(candidates, complete(r)...)
+		}
+	}
+	return start, matching(candidates, word)
+}
+
+// Returns the sorted, distinct candidates starting with prefix
+func matching(candidates []string, prefix string) []string {
+	var matches []string
+	seen := make(map[string]bool)
+	for _, c := range candidates {
+		if strings.HasPrefix(c, prefix) && !seen[c] {
+			seen[c] = true
+			matches = append(matches, c)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
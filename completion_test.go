@@ -0,0 +1,54 @@
+package main
+
+import (
+	"internal/pokecache"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompleteLine(t *testing.T) {
+	r := newREPL(strings.NewReader(""), io.Discard, io.Discard, pokecache.NewCache(0), pokecache.NewCache(0))
+	r.pokedex.Add("pikachu", []byte("{}"))
+	r.pokedex.Add("pidgey", []byte("{}"))
+	r.lastMapPage = []string{"canalave-city-area", "eterna-city-area", "mt-coronet-1f"}
+	r.lastExplored = []string{"tentacool", "tentacruel"}
+
+	cases := []struct {
+		line       string
+		start      int
+		candidates []string
+	}{
+		{"", 0, commandNames()},
+		{"ma", 0, []string{"map", "mapb"}},
+		{"E", 0, nil},
+		{"inspect pi", 8, []string{"pidgey", "pikachu"}},
+		{"inspect pikachu ", 16, nil},
+		{"explore ", 8, []string{"canalave-city-area", "eterna-city-area", "mt-coronet-1f"}},
+		{"explore  e", 9, []string{"eterna-city-area"}},
+		{"catch tentacr", 6, []string{"tentacruel"}},
+		{"cache ", 6, []string{"export", "import", "stats"}},
+		{"cache export ", 13, nil},
+		{"prefetch --", 9, []string{"--workers"}},
+		{"prefetch --workers 4 ", 21, nil},
+		{"help ca", 5, []string{"cache", "catch"}},
+		{"help cache ex", 11, []string{"export"}},
+		{"fly ", 4, nil},
+	}
+	for _, c := range cases {
+		start, candidates := r.completeLine(c.line)
+		if start != c.start {
+			t.Errorf("completeLine(%q) start = %d, want %d", c.line, start, c.start)
+		}
+		if c.line == "" {
+			if len(candidates) != len(c.candidates) {
+				t.Errorf("completeLine(%q) gave %d candidates, want every command", c.line, len(candidates))
+			}
+			continue
+		}
+		if !reflect.DeepEqual(candidates, c.candidates) {
+			t.Errorf("completeLine(%q) = %q, want %q", c.line, candidates, c.candidates)
+		}
+	}
+}
@@ -0,0 +1,510 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"internal/pokeapi"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A configurable value
+// Settings are read from the config file, then POKEDEX_<KEY> environment variables,
+// then --<key> flags given before the command, each overriding the last
+// Only settings without a default may be set to an empty value
+type setting struct {
+	key      string
+	usage    string
+	defValue string
+	// Checks a value before it is used or saved
+	validate func(string) error
+	// Set for settings only read at startup, so changes need a restart
+	startup bool
+	// Set for on/off settings, whose flag turns them on when given without a value
+	boolean bool
+}
+
+// Every setting, in the order config lists them
+var settings = []setting{
+	{key: "base_url", usage: "root of the PokeAPI v2 endpoints, such as a pokedexcli proxy", defValue: pokeapi.DefaultBaseURL, validate: validateURL},
+	{key: "offline_bundle", usage: "cache bundle imported at startup, for working offline", startup: true},
+	{key: "cache_dir", usage: "directory of the persistent response cache; \"off\" keeps responses in memory only", defValue: defaultCacheDir(), startup: true},
+	{key: "cache_ttl", usage: "how long responses are fresh in memory", defValue: "30s", validate: validateDuration, startup: true},
+	{key: "stale_window", usage: "how long expired responses may still be served", defValue: "10m", validate: validateDuration, startup: true},
+	{key: "negative_ttl", usage: "how long not-found responses are remembered", defValue: "5m", validate: validateDuration, startup: true},
+	{key: "disk_ttl", usage: "how long responses are kept in the persistent cache", defValue: "168h", validate: validateDuration, startup: true},
+	{key: "pokedex_file", usage: "file caught pokemon are saved to between sessions; \"off\" keeps them for this session only", defValue: defaultPokedexFile(), startup: true},
+	{key: "page_size", usage: "number of location areas shown by map and mapb", defValue: "20", validate: validatePositive},
+	{key: "language", usage: "language code for localized names", defValue: "en"},
+	{key: "game_version", usage: "game version explore is limited to, such as diamond; empty for all"},
+	{key: "output", usage: "default result format: " + strings.Join(outputFormats, ", "), defValue: "text", validate: validateChoice(outputFormats...)},
+	{key: "color", usage: "when to color output: auto, always or never", defValue: "auto", validate: validateChoice("auto", "always", "never")},
+	{key: "pager", usage: "how the REPL shows output taller than the terminal: auto for $PAGER or the built-in pager, builtin, off, or a command such as \"less -R\"", defValue: "auto", validate: validatePager},
+	{key: "seed", usage: "seed for catch rolls so they repeat; 0 for random", defValue: "0", validate: validateInt},
+	{key: "log_level", usage: "lowest level logged: debug, info, warn or error; -v sets info and -vv debug", defValue: "warn", validate: validateChoice("debug", "info", "warn", "error"), startup: true},
+	{key: "log_file", usage: "file log records are appended to; empty for stderr", startup: true},
+	{key: "trace", usage: "report the API requests made by each command: on or off", defValue: "off", validate: validateChoice("on", "off"), boolean: true},
+	{key: "trace_file", usage: "file each command's trace is appended to as a line of JSON while tracing"},
+}
+
+// Flags given before the command that set log_level
+var verbosityFlags = map[string]string{"-v": "info", "-vv": "debug"}
+
+// Settings in effect and where each came from
+type config struct {
+	// Path of the config file
+	path    string
+	values  map[string]string
+	sources map[string]string
+	// Values held in the config file, which config set and unset rewrite
+	file map[string]string
+	// Problems with the config file, whose entries were ignored
+	warnings []error
+}
+
+// Settings of this process, filled by loadConfig
+var cfg = newConfig("")
+
+// Returns a config holding the default of every setting
+func newConfig(path string) *config {
+	c := &config{path: path, values: make(map[string]string), sources: make(map[string]string), file: make(map[string]string)}
+	for _, s := range settings {
+		c.values[s.key], c.sources[s.key] = s.defValue, "default"
+	}
+	return c
+}
+
+// Returns the setting called key
+func lookupSetting(key string) (setting, bool) {
+	key = strings.ReplaceAll(strings.ToLower(key), "-", "_")
+	for _, s := range settings {
+		if s.key == key {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+// Names of every setting, for tab completion
+func settingKeys() []string {
+	keys := make([]string, len(settings))
+	for i, s := range settings {
+		keys[i] = s.key
+	}
+	return keys
+}
+
+// Returns the default location of the persistent cache, or "off" if there is no user cache directory
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "off"
+	}
+	return filepath.Join(dir, "pokedexcli")
+}
+
+// Returns the pokedex file location: pokedex.bundle in the user data directory, or "off" if there is none
+func defaultPokedexFile() string {
+	dir, err := userDir("XDG_DATA_HOME", ".local/share")
+	if err != nil {
+		return "off"
+	}
+	return filepath.Join(dir, "pokedexcli", "pokedex.bundle")
+}
+
+// Returns a per-user directory for files the program keeps for itself rather than settings:
+// the directory in the XDG environment variable env, or unixDir under the home directory if it is unset
+// Systems without XDG directories use the user config directory
+func userDir(env, unixDir string) (string, error) {
+	switch runtime.GOOS {
+	case "windows", "darwin", "ios", "plan9":
+		return os.UserConfigDir()
+	}
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, filepath.FromSlash(unixDir)), nil
+}
+
+// Returns the config file location: $POKEDEX_CONFIG, or config.json in the user config directory
+func defaultConfigPath() string {
+	if path := os.Getenv("POKEDEX_CONFIG"); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "pokedexcli", "config.json")
+}
+
+// Reads the config file, environment and the flags leading args into cfg and applies them
+// A --config flag names a different config file
+// Returns the args from the command on
+func loadConfig(args []string) ([]string, error) {
+	flags, args, err := parseGlobalFlags(args)
+	if err != nil {
+		return nil, err
+	}
+	path := defaultConfigPath()
+	if p, ok := flags["config"]; ok {
+		path = p
+		delete(flags, "config")
+	}
+	c := newConfig(path)
+	c.readFile()
+	for _, s := range settings {
+		if value, ok := os.LookupEnv("POKEDEX_" + strings.ToUpper(s.key)); ok {
+			if err := c.set(s.key, value, "env"); err != nil {
+				return nil, fmt.Errorf("POKEDEX_%s: %w", strings.ToUpper(s.key), err)
+			}
+		}
+	}
+	for key, value := range flags {
+		if err := c.set(key, value, "flag"); err != nil {
+			return nil, &usageError{msg: fmt.Sprintf("--%s: %s", strings.ReplaceAll(key, "_", "-"), strings.TrimSpace(err.Error()))}
+		}
+	}
+	cfg = c
+	return args, cfg.apply()
+}
+
+// Splits the --<setting>, --config, -v and -vv flags from the start of args
+// Flags are written with dashes or underscores, as --base-url value or --base-url=value
+// Returns the flag values by setting key and the args from the command on
+func parseGlobalFlags(args []string) (map[string]string, []string, error) {
+	flags := make(map[string]string)
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") && args[0] != "-" && args[0] != "--" {
+		if level, ok := verbosityFlags[args[0]]; ok {
+			flags["log_level"] = level
+			args = args[1:]
+			continue
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(args[0], "-"), "=")
+		key := strings.ReplaceAll(name, "-", "_")
+		s, ok := lookupSetting(key)
+		if !ok && key != "config" {
+			// Left for the command, such as -h
+			break
+		}
+		args = args[1:]
+		if !hasValue && s.boolean {
+			value, hasValue = "on", true
+		}
+		if !hasValue {
+			if len(args) == 0 {
+				return nil, nil, &usageError{msg: fmt.Sprintf("flag --%s needs a value", name)}
+			}
+			value, args = args[0], args[1:]
+		}
+		flags[key] = value
+	}
+	return flags, args, nil
+}
+
+// Reads the settings in the config file, which is a JSON object of setting keys to values
+// A missing file leaves the defaults
+// A file that cannot be read, unknown keys and invalid values are recorded in warnings and leave the defaults,
+// so a broken file never stops pokedexcli and can be fixed with config set and unset
+// Entries that were ignored are kept in file, so config set does not drop them
+func (c *config) readFile() {
+	if c.path == "" {
+		return
+	}
+	data, err := os.ReadFile(c.path)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		c.warnings = append(c.warnings, err)
+		return
+	}
+	// Numbers are kept as written so large seeds are not rounded
+	var file map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&file); err != nil {
+		c.warnings = append(c.warnings, fmt.Errorf("%s: %w\n", c.path, err))
+		return
+	}
+	for key, raw := range file {
+		value := fmt.Sprint(raw)
+		if raw == nil {
+			value = ""
+		}
+		if s, ok := lookupSetting(key); ok {
+			key = s.key
+		}
+		c.file[key] = value
+		if err := c.set(key, value, "file"); err != nil {
+			c.warnings = append(c.warnings, fmt.Errorf("%s: %s: %w", c.path, key, err))
+		}
+	}
+}
+
+// Sets the value of the setting called key after checking it, recording where it came from
+func (c *config) set(key string, value string, source string) error {
+	s, ok := lookupSetting(key)
+	if !ok {
+		return fmt.Errorf("unknown setting %q\n", key)
+	}
+	if err := s.check(value); err != nil {
+		return err
+	}
+	c.values[s.key], c.sources[s.key] = value, source
+	return nil
+}
+
+// Checks a value for the setting before it is used or saved
+// An empty value is rejected for settings that have a default, which unsetting them restores
+func (s setting) check(value string) error {
+	if value == "" {
+		if s.defValue != "" {
+			return fmt.Errorf("%s needs a value; unset it to use the default, %q\n", s.key, s.defValue)
+		}
+		return nil
+	}
+	if s.validate != nil {
+		return s.validate(value)
+	}
+	return nil
+}
+
+// Returns the value of the setting called key
+func (c *config) get(key string) string {
+	return c.values[key]
+}
+
+// Returns the setting called key as a duration
+// Values are checked when set, so this cannot fail
+func (c *config) duration(key string) time.Duration {
+	d, _ := time.ParseDuration(c.values[key])
+	return d
+}
+
+// Returns the setting called key as an integer
+func (c *config) int(key string) int64 {
+	n, _ := strconv.ParseInt(c.values[key], 10, 64)
+	return n
+}
+
+// Applies the settings that take effect without restarting
+func (c *config) apply() error {
+	base := c.get("base_url")
+	if base == "" {
+		base = pokeapi.DefaultBaseURL
+	}
+	pokeapi.BaseURL = strings.TrimSuffix(base, "/") + "/"
+	if n := c.int("page_size"); n > 0 {
+		pokeapi.PageSize = int(n)
+	}
+	pokeapi.Language = c.get("language")
+	pokeapi.GameVersion = c.get("game_version")
+	if seed := c.int("seed"); seed != 0 {
+		pokeapi.SetSeed(seed)
+	}
+	format := c.get("output")
+	if format == "" {
+		format = "text"
+	}
+	outputFormat = format
+	return nil
+}
+
+func init() {
+	keyArg := argSpec{name: "key", complete: func(*repl) []string { return settingKeys() }}
+	registry.register(cliCommand{
+		name:        "config",
+		category:    "General",
+		description: "Shows and changes settings",
+		long: "Settings are read from the config file, then POKEDEX_<KEY> environment variables, then --<key> flags\n" +
+			"given before the command, as in \"pokedexcli --language fr explore canalave-city-area\".\n" +
+			"Without a subcommand, lists every setting with its value and where the value came from.",
+		examples: []string{"config", "config set base_url http://localhost:8080/api/v2/", "config unset seed"},
+		callback: (*repl).configList,
+		subcommands: []cliCommand{
+			{name: "list", description: "Lists every setting with its value and where it came from", callback: (*repl).configList},
+			{name: "get", description: "Prints the value of a setting", args: []argSpec{keyArg}, callback: (*repl).configGet},
+			{name: "set", description: "Saves a setting to the config file", args: []argSpec{keyArg, {name: "value"}}, callback: (*repl).configSet},
+			{name: "unset", description: "Removes a setting from the config file", args: []argSpec{keyArg}, callback: (*repl).configUnset},
+			{name: "path", description: "Prints the location of the config file", callback: (*repl).configPath},
+		},
+	})
+}
+
+// Lists every setting with its value, where it came from and what it does
+func (r *repl) configList(arguments commandArgs) error {
+	fmt.Fprintf(r.out, "Config file: %s\n", cfg.path)
+	for _, s := range settings {
+		fmt.Fprintf(r.out, "%-15s = %-30q (%s)\n\t%s\n", s.key, cfg.get(s.key), cfg.sources[s.key], s.usage)
+	}
+	return nil
+}
+
+func (r *repl) configGet(arguments commandArgs) error {
+	s, ok := lookupSetting(arguments.Arg("key"))
+	if !ok {
+		return fmt.Errorf("unknown setting %q\n", arguments.Arg("key"))
+	}
+	fmt.Fprintln(r.out, cfg.get(s.key))
+	return nil
+}
+
+// Saves the setting to the config file and applies it to this session
+// Settings overridden by the environment or a flag keep their overriding value
+func (r *repl) configSet(arguments commandArgs) error {
+	s, ok := lookupSetting(arguments.Arg("key"))
+	if !ok {
+		return fmt.Errorf("unknown setting %q\n", arguments.Arg("key"))
+	}
+	value := arguments.Arg("value")
+	if err := s.check(value); err != nil {
+		return err
+	}
+	cfg.file[s.key] = value
+	if err := cfg.save(); err != nil {
+		return err
+	}
+	return cfg.update(r.out, s, value)
+}
+
+// Removes the setting from the config file, going back to its default in this session
+// Unknown keys can be removed too, if the file has them
+func (r *repl) configUnset(arguments commandArgs) error {
+	s, ok := lookupSetting(arguments.Arg("key"))
+	if _, inFile := cfg.file[arguments.Arg("key")]; !ok && inFile {
+		delete(cfg.file, arguments.Arg("key"))
+		return cfg.save()
+	}
+	if !ok {
+		return fmt.Errorf("unknown setting %q\n", arguments.Arg("key"))
+	}
+	delete(cfg.file, s.key)
+	if err := cfg.save(); err != nil {
+		return err
+	}
+	return cfg.update(r.out, s, s.defValue)
+}
+
+func (r *repl) configPath(arguments commandArgs) error {
+	fmt.Fprintln(r.out, cfg.path)
+	return nil
+}
+
+// Uses value for the setting in this session after it was saved to or removed from the file
+// Prints a note to w if an override or a restart keeps the saved value from taking effect now;
+// the value in use is left as it is, so config list shows what this session uses
+func (c *config) update(w io.Writer, s setting, value string) error {
+	switch source := c.sources[s.key]; {
+	case source == "env" || source == "flag":
+		fmt.Fprintf(w, "Saved; %s is overridden by the %s in this session\n", s.key, map[string]string{"env": "environment", "flag": "flag"}[source])
+		return nil
+	case s.startup:
+		fmt.Fprintf(w, "Saved; %s takes effect the next time pokedexcli starts\n", s.key)
+		return nil
+	}
+	source := "file"
+	if _, inFile := c.file[s.key]; !inFile {
+		source = "default"
+	}
+	c.values[s.key], c.sources[s.key] = value, source
+	return c.apply()
+}
+
+// Writes the settings held in the config file back to it
+// Written to a temporary file first so a failed write leaves the old config intact
+func (c *config) save() error {
+	if c.path == "" {
+		return fmt.Errorf("no config file location; set POKEDEX_CONFIG\n")
+	}
+	data, err := json.MarshalIndent(c.file, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(append(data, '\n'))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
+
+func validateURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q is not an http or https URL\n", value)
+	}
+	return nil
+}
+
+func validateDuration(value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return fmt.Errorf("%q is not a duration such as 30s, 10m or 168h\n", value)
+	}
+	return nil
+}
+
+func validateInt(value string) error {
+	if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+		return fmt.Errorf("%q is not a whole number\n", value)
+	}
+	return nil
+}
+
+func validatePositive(value string) error {
+	if n, err := strconv.ParseInt(value, 10, 64); err != nil || n < 1 {
+		return fmt.Errorf("%q is not a positive whole number\n", value)
+	}
+	return nil
+}
+
+// Checks the pager is auto, builtin, off or a program that can be found
+func validatePager(value string) error {
+	switch value {
+	case "auto", "builtin", "off":
+		return nil
+	}
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return fmt.Errorf("%q is not auto, builtin, off or a command\n", value)
+	}
+	if _, err := exec.LookPath(fields[0]); err != nil {
+		return fmt.Errorf("pager %q cannot be run: %w\n", fields[0], err)
+	}
+	return nil
+}
+
+// Returns a check that the value is one of choices
+func validateChoice(choices ...string) func(string) error {
+	return func(value string) error {
+		for _, choice := range choices {
+			if value == choice {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q is not one of %s\n", value, strings.Join(choices, ", "))
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"internal/pokeapi"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Points loadConfig at a config file holding contents and restores the settings after the test
+func useConfigFile(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if contents != "" {
+		if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("POKEDEX_CONFIG", path)
+	for _, s := range settings {
+		os.Unsetenv("POKEDEX_" + strings.ToUpper(s.key))
+	}
+	t.Cleanup(func() {
+		cfg = newConfig("")
+		cfg.apply()
+		pokeapi.SetSeed(time.Now().UnixNano())
+	})
+	return path
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	useConfigFile(t, `{"language": "fr", "page_size": 5, "seed": 12345678901, "game_version": "pearl", "base_url": "http://mirror:8080/api/v2"}`)
+	t.Setenv("POKEDEX_GAME_VERSION", "diamond")
+	t.Setenv("POKEDEX_OUTPUT", "json")
+
+	args, err := loadConfig([]string{"--output", "ndjson", "--cache-ttl=1m", "explore", "--output", "yaml", "canalave-city-area"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"explore", "--output", "yaml", "canalave-city-area"}; !reflect.DeepEqual(args, want) {
+		t.Errorf("args %q, want %q", args, want)
+	}
+	cases := []struct{ key, value, source string }{
+		{"language", "fr", "file"},
+		{"page_size", "5", "file"},
+		{"seed", "12345678901", "file"},
+		{"game_version", "diamond", "env"},
+		{"output", "ndjson", "flag"},
+		{"cache_ttl", "1m", "flag"},
+		{"color", "auto", "default"},
+	}
+	for _, c := range cases {
+		if cfg.get(c.key) != c.value || cfg.sources[c.key] != c.source {
+			t.Errorf("%s = %q from %s, want %q from %s", c.key, cfg.get(c.key), cfg.sources[c.key], c.value, c.source)
+		}
+	}
+	if pokeapi.BaseURL != "http://mirror:8080/api/v2/" || pokeapi.PageSize != 5 || pokeapi.Language != "fr" || pokeapi.GameVersion != "diamond" || outputFormat != "ndjson" {
+		t.Errorf("settings not applied: %s %d %s %s %s", pokeapi.BaseURL, pokeapi.PageSize, pokeapi.Language, pokeapi.GameVersion, outputFormat)
+	}
+	if cfg.duration("cache_ttl") != time.Minute {
+		t.Errorf("cache_ttl = %s, want 1m", cfg.duration("cache_ttl"))
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	cases := []struct {
+		file string
+		env  string
+		args []string
+		want string
+	}{
+		{env: "COLOR=sometimes", want: "POKEDEX_COLOR"},
+		{env: "LOG_LEVEL=", want: "POKEDEX_LOG_LEVEL: log_level needs a value"},
+		{args: []string{"--base-url", "ftp://example.com"}, want: "--base-url"},
+		{args: []string{"--page-size"}, want: "needs a value"},
+		{args: []string{"--cache-ttl="}, want: "--cache-ttl: cache_ttl needs a value"},
+	}
+	for _, c := range cases {
+		useConfigFile(t, c.file)
+		key, value, _ := strings.Cut(c.env, "=")
+		if key != "" {
+			t.Setenv("POKEDEX_"+key, value)
+		}
+		_, err := loadConfig(c.args)
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("loadConfig with %q %q %q = %v, want error containing %q", c.file, c.env, c.args, err, c.want)
+		}
+		if key != "" {
+			os.Unsetenv("POKEDEX_" + key)
+		}
+	}
+}
+
+func TestLoadConfigIgnoresBadFileEntries(t *testing.T) {
+	cases := []struct {
+		file string
+		want string
+		fix  string
+	}{
+		{file: `{"colour": "never", "language": "fr"}`, want: `unknown setting "colour"`, fix: "config unset colour"},
+		{file: `{"cache_ttl": "soon", "language": "fr"}`, want: `"soon" is not a duration`, fix: "config unset cache_ttl"},
+		{file: `{"cache_ttl": "", "language": "fr"}`, want: "cache_ttl needs a value", fix: "config unset cache_ttl"},
+		{file: `not json`, want: "config.json", fix: "config set language en"},
+	}
+	for _, c := range cases {
+		path := useConfigFile(t, c.file)
+		if _, err := loadConfig([]string{"--page-size", "3"}); err != nil {
+			t.Errorf("loadConfig with %q = %v, want nil error", c.file, err)
+			continue
+		}
+		if len(cfg.warnings) != 1 || !strings.Contains(cfg.warnings[0].Error(), c.want) {
+			t.Errorf("loadConfig with %q warned %v, want one warning containing %q", c.file, cfg.warnings, c.want)
+		}
+		if cfg.get("cache_ttl") != "30s" || cfg.get("color") != "auto" || cfg.get("page_size") != "3" {
+			t.Errorf("loadConfig with %q: cache_ttl %s, color %s, page_size %s, want defaults and the flag", c.file, cfg.get("cache_ttl"), cfg.get("color"), cfg.get("page_size"))
+		}
+		if strings.Contains(c.file, "language") && cfg.get("language") != "fr" {
+			t.Errorf("loadConfig with %q dropped the valid language setting", c.file)
+		}
+
+		// The file can still be fixed from the command line
+		r := newREPL(strings.NewReader(""), io.Discard, io.Discard, nil, nil)
+		for _, line := range []string{"config path", c.fix} {
+			if err := r.runLine(line); err != nil {
+				t.Errorf("%s with %q: %v", line, c.file, err)
+			}
+		}
+		if data, _ := os.ReadFile(path); strings.Contains(string(data), "colour") || strings.Contains(string(data), "soon") || strings.Contains(string(data), "not json") {
+			t.Errorf("config file still holds the bad entry:\n%s", data)
+		}
+	}
+}
+
+func TestConfigSetStartupSetting(t *testing.T) {
+	useConfigFile(t, "")
+	if _, err := loadConfig(nil); err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	r := newREPL(strings.NewReader(""), &out, io.Discard, nil, nil)
+	if err := r.runLine("config set cache_ttl 5m"); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.get("cache_ttl") != "30s" || cfg.sources["cache_ttl"] != "default" {
+		t.Errorf("cache_ttl = %s from %s, want the 30s in use until restart", cfg.get("cache_ttl"), cfg.sources["cache_ttl"])
+	}
+	if cfg.file["cache_ttl"] != "5m" || !strings.Contains(out.String(), "next time") {
+		t.Errorf("cache_ttl not saved for the next start: file %q, output %q", cfg.file["cache_ttl"], out.String())
+	}
+}
+
+func TestConfigSetAndUnset(t *testing.T) {
+	path := useConfigFile(t, `{"language": "fr"}`)
+	if _, err := loadConfig([]string{"--page-size", "7"}); err != nil {
+		t.Fatal(err)
+	}
+	r := newREPL(strings.NewReader(""), io.Discard, io.Discard, nil, nil)
+	for _, line := range []string{"config set seed 99", "config set page_size 3", "config unset language", "config set base_url http://localhost:8080/api/v2/"} {
+		if err := r.runLine(line); err != nil {
+			t.Fatalf("%s: %v", line, err)
+		}
+	}
+	if err := r.runLine("config set page_size lots"); err == nil {
+		t.Error("config set with an invalid value succeeded")
+	}
+	if err := r.runLine(`config set log_level ""`); err == nil {
+		t.Error("config set of a setting with a default to an empty value succeeded")
+	}
+	if err := r.runLine(`config set game_version ""`); err != nil {
+		t.Errorf("config set of a setting without a default to an empty value: %v", err)
+	}
+	if err := r.runLine("config set colour never"); err == nil {
+		t.Error("config set of an unknown setting succeeded")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "{\n  \"base_url\": \"http://localhost:8080/api/v2/\",\n  \"game_version\": \"\",\n  \"page_size\": \"3\",\n  \"seed\": \"99\"\n}\n"
+	if string(data) != want {
+		t.Errorf("config file =\n%s\nwant\n%s", data, want)
+	}
+	if pokeapi.Language != "en" || pokeapi.BaseURL != "http://localhost:8080/api/v2/" {
+		t.Errorf("language %s and base url %s not updated in the session", pokeapi.Language, pokeapi.BaseURL)
+	}
+	if pokeapi.PageSize != 7 {
+		t.Errorf("page size %d, want the flag's 7 to stay in effect", pokeapi.PageSize)
+	}
+
+	reloaded, err := loadConfig(nil)
+	if err != nil || len(reloaded) != 0 {
+		t.Fatal(err)
+	}
+	if cfg.get("seed") != "99" || cfg.get("page_size") != "3" || cfg.sources["language"] != "default" {
+		t.Errorf("reloaded seed %s, page size %s, language from %s", cfg.get("seed"), cfg.get("page_size"), cfg.sources["language"])
+	}
+}
+
+func TestParseGlobalFlags(t *testing.T) {
+	flags, rest, err := parseGlobalFlags([]string{"--page_size", "5", "--game-version=diamond", "--config", "/tmp/c.json", "-h", "--output", "json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"page_size": "5", "game_version": "diamond", "config": "/tmp/c.json"}
+	if !reflect.DeepEqual(flags, want) {
+		t.Errorf("flags %v, want %v", flags, want)
+	}
+	if wantRest := []string{"-h", "--output", "json"}; !reflect.DeepEqual(rest, wantRest) {
+		t.Errorf("rest %q, want %q", rest, wantRest)
+	}
+}
+
+func TestValidatePager(t *testing.T) {
+	for _, value := range []string{"auto", "builtin", "off", "cat", "cat -n"} {
+		if err := validatePager(value); err != nil {
+			t.Errorf("validatePager(%q) = %v, want nil", value, err)
+		}
+	}
+	for _, value := range []string{"lesss -R", "   "} {
+		if err := validatePager(value); err == nil {
+			t.Errorf("validatePager(%q) = nil, want error", value)
+		}
+	}
+}
+
+func TestUserDir(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("XDG directories are only used on Unix systems")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_STATE_HOME", "")
+	if dir, err := userDir("XDG_STATE_HOME", ".local/state"); err != nil || dir != filepath.Join(home, ".local", "state") {
+		t.Errorf("userDir without XDG_STATE_HOME = %q, %v", dir, err)
+	}
+	t.Setenv("XDG_STATE_HOME", "relative")
+	if dir, _ := userDir("XDG_STATE_HOME", ".local/state"); dir != filepath.Join(home, ".local", "state") {
+		t.Errorf("userDir with a relative XDG_STATE_HOME = %q, want it ignored", dir)
+	}
+	state := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", state)
+	if dir, _ := userDir("XDG_STATE_HOME", ".local/state"); dir != state {
+		t.Errorf("userDir = %q, want %q", dir, state)
+	}
+}
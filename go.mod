@@ -6,6 +6,10 @@ require internal/pokeapi v1.0.0
 
 require internal/pokecache v1.0.0
 
+require internal/lineedit v1.0.0
+
 replace internal/pokeapi => ./internal/pokeapi
 
 replace internal/pokecache => ./internal/pokecache
+
+replace internal/lineedit => ./internal/lineedit
@@ -0,0 +1,3 @@
+module lineedit
+
+go 1.21.7
@@ -0,0 +1,121 @@
+package lineedit
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Lines entered previously, oldest first, optionally persisted to a file
+// Each added line is appended to the file, so sessions running side by side all keep theirs
+type History struct {
+	entries []string
+	max     int
+	path    string
+}
+
+// Returns an in-memory history holding at most max lines
+func NewHistory(max int) *History {
+	return &History{max: max}
+}
+
+// Loads the history kept in the file at path, holding at most max lines
+// A missing file gives an empty history that is created on the first Add
+// Files that have grown well past max lines are rewritten with only the newest
+func LoadHistory(path string, max int) (*History, error) {
+	h := &History{max: max, path: path}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		h.entries = append(h.entries, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(h.entries) > 2*max {
+		h.entries = h.entries[len(h.entries)-max:]
+		if err := h.rewrite(); err != nil {
+			return nil, err
+		}
+	}
+	h.trim()
+	return h, nil
+}
+
+// Reports the number of lines held
+// A nil history holds none
+func (h *History) Len() int {
+	if h == nil {
+		return 0
+	}
+	return len(h.entries)
+}
+
+// Returns the lines held, oldest first
+func (h *History) Entries() []string {
+	if h == nil {
+		return nil
+	}
+	return append([]string(nil), h.entries...)
+}
+
+// Adds line to the end of the history and its file
+// Blank lines and repeats of the previous line are skipped
+func (h *History) Add(line string) error {
+	if h == nil || strings.TrimSpace(line) == "" || strings.ContainsAny(line, "\r\n") {
+		return nil
+	}
+	if n := len(h.entries); n > 0 && h.entries[n-1] == line {
+		return nil
+	}
+	h.entries = append(h.entries, line)
+	h.trim()
+	if h.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(h.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(line + "\n")
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Drops the oldest lines beyond max
+func (h *History) trim() {
+	if h.max > 0 && len(h.entries) > h.max {
+		h.entries = h.entries[len(h.entries)-h.max:]
+	}
+}
+
+// Replaces the file with the lines held
+// Written to a temporary file first so a failed write leaves the old history intact
+func (h *History) rewrite() error {
+	tmp, err := os.CreateTemp(filepath.Dir(h.path), filepath.Base(h.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.WriteString(strings.Join(h.entries, "\n") + "\n")
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), h.path)
+}
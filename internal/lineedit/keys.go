@@ -0,0 +1,145 @@
+package lineedit
+
+// Editing action read from the terminal
+type keyCode int
+
+const (
+	keyUnknown keyCode = iota
+	keyRune
+	keyEnter
+	keyTab
+	keyBackspace
+	keyDelete
+	keyLeft
+	keyRight
+	keyUp
+	keyDown
+	keyHome
+	keyEnd
+	keyWordLeft
+	keyWordRight
+	keyKillEnd
+	keyKillStart
+	keyKillWord
+	keyClear
+	keySearch
+	keyCancel
+	keyCtrlC
+	keyCtrlD
+	keyPageUp
+	keyPageDown
+)
+
+type key struct {
+	code keyCode
+	r    rune
+}
+
+// Control characters and the keys they are bound to, following readline
+var controlKeys = map[rune]keyCode{
+	1:   keyHome,      // Ctrl-A
+	2:   keyLeft,      // Ctrl-B
+	3:   keyCtrlC,     // Ctrl-C
+	4:   keyCtrlD,     // Ctrl-D
+	5:   keyEnd,       // Ctrl-E
+	6:   keyRight,     // Ctrl-F
+	7:   keyCancel,    // Ctrl-G
+	8:   keyBackspace, // Ctrl-H
+	9:   keyTab,
+	10:  keyEnter,
+	11:  keyKillEnd, // Ctrl-K
+	12:  keyClear,   // Ctrl-L
+	13:  keyEnter,
+	14:  keyDown,      // Ctrl-N
+	16:  keyUp,        // Ctrl-P
+	18:  keySearch,    // Ctrl-R
+	21:  keyKillStart, // Ctrl-U
+	23:  keyKillWord,  // Ctrl-W
+	127: keyBackspace,
+}
+
+// Final bytes of CSI and SS3 escape sequences and their keys
+var escapeKeys = map[byte]keyCode{
+	'A': keyUp,
+	'B': keyDown,
+	'C': keyRight,
+	'D': keyLeft,
+	'H': keyHome,
+	'F': keyEnd,
+}
+
+// Parameters of "ESC [ n ~" sequences and their keys
+var tildeKeys = map[string]keyCode{
+	"1": keyHome,
+	"7": keyHome,
+	"3": keyDelete,
+	"4": keyEnd,
+	"8": keyEnd,
+	"5": keyPageUp,
+	"6": keyPageDown,
+}
+
+// Reads the next key, decoding escape sequences
+func (e *Editor) readKey() (key, error) {
+	r, _, err := e.in.ReadRune()
+	if err != nil {
+		return key{}, err
+	}
+	if r == 27 {
+		return e.readEscape()
+	}
+	if code, ok := controlKeys[r]; ok {
+		return key{code: code}, nil
+	}
+	if r < 32 {
+		return key{code: keyUnknown}, nil
+	}
+	return key{code: keyRune, r: r}, nil
+}
+
+// Decodes the rest of an escape sequence
+// Alt-b and Alt-f move by words; Ctrl with an arrow key does too
+func (e *Editor) readEscape() (key, error) {
+	b, err := e.in.ReadByte()
+	if err != nil {
+		return key{}, err
+	}
+	switch b {
+	case 'b':
+		return key{code: keyWordLeft}, nil
+	case 'f':
+		return key{code: keyWordRight}, nil
+	case '[', 'O':
+	default:
+		return key{code: keyUnknown}, nil
+	}
+	var params []byte
+	for {
+		c, err := e.in.ReadByte()
+		if err != nil {
+			return key{}, err
+		}
+		if c >= 0x40 && c <= 0x7e {
+			return escapeKey(string(params), c), nil
+		}
+		params = append(params, c)
+	}
+}
+
+// Returns the key for an escape sequence with the given parameters and final byte
+func escapeKey(params string, final byte) key {
+	if final == '~' {
+		return key{code: tildeKeys[params]}
+	}
+	code := escapeKeys[final]
+	// "1;5" marks Ctrl held with the key
+	if params == "1;5" {
+		switch code {
+		case keyLeft:
+			code = keyWordLeft
+		case keyRight:
+			code = keyWordRight
+		}
+	}
+	return key{code: code}
+}
@@ -0,0 +1,340 @@
+// Package lineedit reads lines from a terminal with cursor movement, history,
+// reverse search and tab completion
+// When the input is not a terminal it reads plain lines instead
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Returned by ReadLine when the user presses Ctrl-C
+var ErrInterrupted = errors.New("interrupted")
+
+// Returns the candidates for completing line
+// Each candidate replaces line[start:]
+type Completer func(line string) (start int, candidates []string)
+
+// Reads lines from in, echoing edits to out
+type Editor struct {
+	// Previous lines, navigated with the arrow keys and searched with Ctrl-R; may be nil
+	History *History
+	// Called on Tab; may be nil
+	Completer Completer
+
+	in          *bufio.Reader
+	out         io.Writer
+	fd          int
+	interactive bool
+}
+
+// Returns an editor reading from in
+// Lines are edited in raw mode only when in is a terminal
+func New(in io.Reader, out io.Writer) *Editor {
+	e := &Editor{in: bufio.NewReader(in), out: out}
+	if f, ok := in.(*os.File); ok && IsTerminal(int(f.Fd())) {
+		e.fd = int(f.Fd())
+		e.interactive = true
+	}
+	return e
+}
+
+// Reports whether lines are edited in the terminal rather than read plainly
+func (e *Editor) Interactive() bool {
+	return e.interactive
+}
+
+// Prints prompt and returns the next line without its line ending
+// Returns io.EOF at the end of input or on Ctrl-D with an empty line,
+// and ErrInterrupted on Ctrl-C
+func (e *Editor) ReadLine(prompt string) (string, error) {
+	if !e.interactive {
+		fmt.Fprint(e.out, prompt)
+		return e.readPlain()
+	}
+	restore, err := makeRaw(e.fd)
+	if err != nil {
+		fmt.Fprint(e.out, prompt)
+		return e.readPlain()
+	}
+	defer restore()
+	return e.edit(prompt)
+}
+
+// Reads a line without editing
+// A final line without a line ending is returned before io.EOF
+func (e *Editor) readPlain() (string, error) {
+	line, err := e.in.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+// State of the line being edited
+type lineState struct {
+	prompt string
+	buf    []rune
+	pos    int
+	// Index into the history being shown, len(entries) for the new line
+	histPos int
+	// The new line, kept while browsing history
+	saved []rune
+	// Set after a Tab that listed nothing, so a second Tab lists the candidates
+	tabbed bool
+}
+
+// Edits a line in raw mode until it is accepted
+func (e *Editor) edit(prompt string) (string, error) {
+	s := &lineState{prompt: prompt, histPos: e.History.Len()}
+	e.refresh(s)
+	for {
+		k, err := e.readKey()
+		if err != nil {
+			return "", err
+		}
+		tabbed := s.tabbed
+		s.tabbed = false
+		switch k.code {
+		case keyEnter:
+			fmt.Fprint(e.out, "\r\n")
+			return string(s.buf), nil
+		case keyCtrlC:
+			fmt.Fprint(e.out, "^C\r\n")
+			return "", ErrInterrupted
+		case keyCtrlD:
+			if len(s.buf) == 0 {
+				fmt.Fprint(e.out, "\r\n")
+				return "", io.EOF
+			}
+			s.deleteAt(s.pos)
+		case keyRune:
+			s.insert(k.r)
+		case keyBackspace:
+			if s.pos > 0 {
+				s.pos--
+				s.deleteAt(s.pos)
+			}
+		case keyDelete:
+			s.deleteAt(s.pos)
+		case keyLeft:
+			if s.pos > 0 {
+				s.pos--
+			}
+		case keyRight:
+			if s.pos < len(s.buf) {
+				s.pos++
+			}
+		case keyHome:
+			s.pos = 0
+		case keyEnd:
+			s.pos = len(s.buf)
+		case keyWordLeft:
+			s.pos = s.wordStart()
+		case keyWordRight:
+			s.pos = s.wordEnd()
+		case keyKillEnd:
+			s.buf = s.buf[:s.pos]
+		case keyKillStart:
+			s.buf = append([]rune(nil), s.buf[s.pos:]...)
+			s.pos = 0
+		case keyKillWord:
+			start := s.wordStart()
+			s.buf = append(s.buf[:start], s.buf[s.pos:]...)
+			s.pos = start
+		case keyUp:
+			e.showHistory(s, s.histPos-1)
+		case keyDown:
+			e.showHistory(s, s.histPos+1)
+		case keyClear:
+			fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+		case keyTab:
+			s.tabbed = e.complete(s, tabbed)
+		case keySearch:
+			line, accepted, err := e.search(s)
+			if err != nil {
+				return "", err
+			}
+			if accepted {
+				fmt.Fprint(e.out, "\r\n")
+				return line, nil
+			}
+		}
+		e.refresh(s)
+	}
+}
+
+// Redraws the prompt and line and places the cursor
+func (e *Editor) refresh(s *lineState) {
+	e.draw(s.prompt, string(s.buf), len([]rune(s.prompt))+s.pos)
+}
+
+// Redraws the whole line as text with the cursor cursor runes in
+func (e *Editor) draw(text string, line string, cursor int) {
+	fmt.Fprintf(e.out, "\r%s%s\x1b[K\r", text, line)
+	if cursor > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dC", cursor)
+	}
+}
+
+func (s *lineState) insert(r rune) {
+	s.buf = append(s.buf, 0)
+	copy(s.buf[s.pos+1:], s.buf[s.pos:])
+	s.buf[s.pos] = r
+	s.pos++
+}
+
+func (s *lineState) deleteAt(i int) {
+	if i < len(s.buf) {
+		s.buf = append(s.buf[:i], s.buf[i+1:]...)
+	}
+}
+
+// Returns the start of the word before the cursor
+func (s *lineState) wordStart() int {
+	i := s.pos
+	for i > 0 && unicode.IsSpace(s.buf[i-1]) {
+		i--
+	}
+	for i > 0 && !unicode.IsSpace(s.buf[i-1]) {
+		i--
+	}
+	return i
+}
+
+// Returns the end of the word after the cursor
+func (s *lineState) wordEnd() int {
+	i := s.pos
+	for i < len(s.buf) && unicode.IsSpace(s.buf[i]) {
+		i++
+	}
+	for i < len(s.buf) && !unicode.IsSpace(s.buf[i]) {
+		i++
+	}
+	return i
+}
+
+// Replaces the line with history entry i, or the new line when i is past the end
+func (e *Editor) showHistory(s *lineState, i int) {
+	n := e.History.Len()
+	if i < 0 || i > n {
+		return
+	}
+	if s.histPos == n {
+		s.saved = s.buf
+	}
+	s.histPos = i
+	if i == n {
+		s.buf = s.saved
+	} else {
+		s.buf = []rune(e.History.entries[i])
+	}
+	s.pos = len(s.buf)
+}
+
+// Completes the text before the cursor
+// A single candidate is inserted with a trailing space; several are completed
+// to their common prefix, and listed if there is none to add and listAll is set
+// Returns true if several candidates remain and none were listed
+func (e *Editor) complete(s *lineState, listAll bool) bool {
+	if e.Completer == nil {
+		return false
+	}
+	head := string(s.buf[:s.pos])
+	start, candidates := e.Completer(head)
+	if len(candidates) == 0 || start < 0 || start > len(head) {
+		return false
+	}
+	word := head[start:]
+	replacement := candidates[0]
+	if len(candidates) == 1 {
+		replacement += " "
+	} else {
+		replacement = commonPrefix(candidates)
+	}
+	if replacement == word && len(candidates) > 1 {
+		if !listAll {
+			return true
+		}
+		fmt.Fprintf(e.out, "\r\n%s\r\n", strings.Join(candidates, "  "))
+		return false
+	}
+	tail := s.buf[s.pos:]
+	s.buf = append([]rune(head[:start]+replacement), tail...)
+	s.pos = len([]rune(head[:start] + replacement))
+	return false
+}
+
+// Returns the longest prefix shared by every string in words
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, w := range words[1:] {
+		for !strings.HasPrefix(w, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
+// Searches the history backwards for lines containing what the user types
+// Ctrl-R finds the next older match, Backspace shortens the query and Ctrl-G cancels
+// Enter accepts the match as the line; any other key leaves the match on the line for editing
+// Returns the match and true if it was accepted with Enter
+func (e *Editor) search(s *lineState) (string, bool, error) {
+	var query []rune
+	match := e.History.Len()
+	found := ""
+	draw := func() {
+		status := "reverse-i-search"
+		if match < 0 {
+			status = "failing reverse-i-search"
+		}
+		text := fmt.Sprintf("(%s)`%s': ", status, string(query))
+		e.draw(text, found, len([]rune(text)))
+	}
+	find := func(from int) {
+		for i := from; i >= 0; i-- {
+			if strings.Contains(e.History.entries[i], string(query)) {
+				match, found = i, e.History.entries[i]
+				return
+			}
+		}
+		match = -1
+	}
+	draw()
+	for {
+		k, err := e.readKey()
+		if err != nil {
+			return "", false, err
+		}
+		switch k.code {
+		case keyRune:
+			query = append(query, k.r)
+			find(min(match, e.History.Len()-1))
+		case keyBackspace:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+			}
+			find(e.History.Len() - 1)
+		case keySearch:
+			if match > 0 {
+				find(match - 1)
+			}
+		case keyEnter:
+			return found, true, nil
+		case keyCtrlC, keyCancel:
+			return "", false, nil
+		default:
+			s.buf = []rune(found)
+			s.pos = len(s.buf)
+			s.histPos = e.History.Len()
+			return "", false, nil
+		}
+		draw()
+	}
+}
@@ -0,0 +1,275 @@
+package lineedit
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Returns an editor reading keys as if from a terminal in raw mode
+// Tests call edit directly, as ReadLine would fail to put the fake input into raw mode
+func fakeEditor(keys string, history ...string) (*Editor, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	e := &Editor{in: bufio.NewReader(strings.NewReader(keys)), out: out, interactive: true}
+	e.History = NewHistory(100)
+	for _, line := range history {
+		e.History.Add(line)
+	}
+	return e, out
+}
+
+const (
+	up        = "\x1b[A"
+	down      = "\x1b[B"
+	right     = "\x1b[C"
+	left      = "\x1b[D"
+	home      = "\x1b[H"
+	deleteKey = "\x1b[3~"
+	ctrlLeft  = "\x1b[1;5D"
+)
+
+func TestEditing(t *testing.T) {
+	cases := []struct {
+		name string
+		keys string
+		want string
+	}{
+		{"plain", "map\r", "map"},
+		{"backspace", "mapx\x7f\r", "map"},
+		{"insert in middle", "cach" + left + left + "x\r", "caxch"},
+		{"home and end", "atch" + home + "c\x05!\r", "catch!"},
+		{"ctrl-a and delete", "xmap\x01" + deleteKey + "\r", "map"},
+		{"kill to end", "explore mt-moon\x01" + right + right + right + right + right + right + right + "\x0b\r", "explore"},
+		{"kill to start", "junk map" + left + left + left + "\x15\r", "map"},
+		{"kill word", "catch pikachu\x17mew\r", "catch mew"},
+		{"word left", "catch pikachu" + ctrlLeft + "\x0b\x1bbx\r", "xcatch "},
+		{"unicode", "catch flabébé\x7f\x7f\r", "catch flabé"},
+		{"unknown keys ignored", "m\x1b[5~a\x00p\r", "map"},
+	}
+	for _, c := range cases {
+		e, _ := fakeEditor(c.keys)
+		got, err := e.edit("> ")
+		if err != nil || got != c.want {
+			t.Errorf("%s: ReadLine = %q, %v, want %q", c.name, got, err, c.want)
+		}
+	}
+}
+
+func TestCtrlCAndCtrlD(t *testing.T) {
+	e, _ := fakeEditor("map\x03")
+	if _, err := e.edit("> "); !errors.Is(err, ErrInterrupted) {
+		t.Errorf("Ctrl-C gave %v, want ErrInterrupted", err)
+	}
+	e, _ = fakeEditor("\x04")
+	if _, err := e.edit("> "); err != io.EOF {
+		t.Errorf("Ctrl-D on an empty line gave %v, want io.EOF", err)
+	}
+	e, _ = fakeEditor("maps\x02\x04\r")
+	if got, _ := e.edit("> "); got != "map" {
+		t.Errorf("Ctrl-D on a line gave %q, want the character under the cursor deleted", got)
+	}
+	e, _ = fakeEditor("map")
+	if _, err := e.edit("> "); err != io.EOF {
+		t.Errorf("end of input gave %v, want io.EOF", err)
+	}
+}
+
+func TestHistoryNavigation(t *testing.T) {
+	e, _ := fakeEditor(up+up+"\r", "map", "explore mt-moon")
+	if got, _ := e.edit("> "); got != "map" {
+		t.Errorf("two ups gave %q, want map", got)
+	}
+	e, _ = fakeEditor("cat"+up+up+up+down+down+"ch\r", "map", "explore mt-moon")
+	if got, _ := e.edit("> "); got != "catch" {
+		t.Errorf("browsing history lost the new line: got %q", got)
+	}
+	e, _ = fakeEditor(up + "\r")
+	e.History = nil
+	if got, err := e.edit("> "); got != "" || err != nil {
+		t.Errorf("up without history gave %q, %v", got, err)
+	}
+}
+
+func TestReverseSearch(t *testing.T) {
+	history := []string{"explore mt-moon", "catch pikachu", "explore canalave-city-area", "map"}
+	cases := []struct {
+		name string
+		keys string
+		want string
+	}{
+		{"newest match", "\x12exp\r", "explore canalave-city-area"},
+		{"older match", "\x12exp\x12\r", "explore mt-moon"},
+		{"backspace widens", "\x12pik\x7f\x7f\x7fmt\r", "explore mt-moon"},
+		{"cancel", "inspect\x12exp\x07\r", "inspect"},
+		{"edit match", "\x12catch" + right + "\x17bulbasaur\r", "catch bulbasaur"},
+		{"no match", "\x12zzz\r", ""},
+	}
+	for _, c := range cases {
+		e, _ := fakeEditor(c.keys, history...)
+		got, err := e.edit("> ")
+		if err != nil || got != c.want {
+			t.Errorf("%s: ReadLine = %q, %v, want %q", c.name, got, err, c.want)
+		}
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	words := []string{"catch", "cache", "map", "mapb"}
+	completer := func(line string) (int, []string) {
+		start := strings.LastIndex(line, " ") + 1
+		var candidates []string
+		for _, w := range words {
+			if strings.HasPrefix(w, line[start:]) {
+				candidates = append(candidates, w)
+			}
+		}
+		return start, candidates
+	}
+	cases := []struct {
+		name string
+		keys string
+		want string
+	}{
+		{"single candidate", "cat\t\r", "catch "},
+		{"later word", "help cac\t\r", "help cache "},
+		{"common prefix", "m\t\r", "map"},
+		{"mid line", "x" + left + "cat\t\r", "catch x"},
+		{"nothing", "zz\t\r", "zz"},
+	}
+	for _, c := range cases {
+		e, _ := fakeEditor(c.keys)
+		e.Completer = completer
+		got, err := e.edit("> ")
+		if err != nil || got != c.want {
+			t.Errorf("%s: ReadLine = %q, %v, want %q", c.name, got, err, c.want)
+		}
+	}
+
+	e, out := fakeEditor("map\t\t\r")
+	e.Completer = completer
+	e.edit("> ")
+	if !strings.Contains(out.String(), "map  mapb") {
+		t.Errorf("second tab did not list candidates:\n%q", out.String())
+	}
+}
+
+func TestPlainInput(t *testing.T) {
+	out := &bytes.Buffer{}
+	e := New(strings.NewReader("map\r\nexplore mt-moon\ncatch"), out)
+	if e.Interactive() {
+		t.Fatal("a reader was treated as a terminal")
+	}
+	var got []string
+	for {
+		line, err := e.ReadLine("> ")
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, line)
+	}
+	if want := []string{"map", "explore mt-moon", "catch"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("read %q, want %q", got, want)
+	}
+	if out.String() != "> > > > " {
+		t.Errorf("printed %q, want a prompt per read", out.String())
+	}
+}
+
+func TestHistoryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "history")
+	h, err := LoadHistory(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"map", "map", " ", "explore mt-moon", "catch\npikachu", "pokedex", "inspect mew"} {
+		if err := h.Add(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{"explore mt-moon", "pokedex", "inspect mew"}
+	if !reflect.DeepEqual(h.Entries(), want) {
+		t.Errorf("entries %q, want %q", h.Entries(), want)
+	}
+	loaded, err := LoadHistory(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.Entries(), want) {
+		t.Errorf("reloaded entries %q, want %q", loaded.Entries(), want)
+	}
+}
+
+func TestHistoryFileCompacted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	if err := os.WriteFile(path, []byte("a\nb\nc\nd\ne\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	h, err := LoadHistory(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"d", "e"}; !reflect.DeepEqual(h.Entries(), want) {
+		t.Errorf("entries %q, want %q", h.Entries(), want)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "d\ne\n" {
+		t.Errorf("file holds %q, want it compacted", data)
+	}
+}
+
+func TestPager(t *testing.T) {
+	lines := []string{"1", "2", "3", "4", "5", "6", "7", "8", "9"}
+	more := func(percent int) string {
+		return fmt.Sprintf("\x1b[7m--More-- (%d%%)\x1b[0m\r\x1b[K", percent)
+	}
+	cases := []struct {
+		name string
+		keys string
+		want string
+	}{
+		{"screens", "  ", "1\n2\n3\n" + more(33) + "4\n5\n6\n" + more(66) + "7\n8\n9\n"},
+		{"line then quit", "\rq", "1\n2\n3\n" + more(33) + "4\n" + more(44)},
+		{"back a screen", " b\x1b[6~\x1b[6~", "1\n2\n3\n" + more(33) + "4\n5\n6\n" + more(66) + "1\n2\n3\n" + more(33) + "4\n5\n6\n" + more(66) + "7\n8\n9\n"},
+		{"end of input", "x", "1\n2\n3\n" + more(33) + more(33)},
+	}
+	for _, c := range cases {
+		e, out := fakeEditor(c.keys)
+		if err := e.page(lines, 4); err != nil {
+			t.Errorf("%s: page error %v", c.name, err)
+		}
+		if out.String() != c.want {
+			t.Errorf("%s: pager wrote\n%q\nwant\n%q", c.name, out.String(), c.want)
+		}
+	}
+
+	e, out := fakeEditor("")
+	e.interactive = false
+	if err := e.Page(lines, 4); err != nil || out.String() != strings.Join(lines, "\n")+"\n" {
+		t.Errorf("Page without a terminal wrote %q, %v, want every line", out.String(), err)
+	}
+}
+
+func TestReadKey(t *testing.T) {
+	e, _ := fakeEditor("j" + up + "\r\x03\x1b[6~\x10\x1b[9~")
+	var names []string
+	for {
+		name, err := e.ReadKey()
+		if err != nil {
+			break
+		}
+		names = append(names, name)
+	}
+	if want := []string{"j", "up", "enter", "ctrl-c", "pgdown", "up", ""}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ReadKey read %q, want %q", names, want)
+	}
+}
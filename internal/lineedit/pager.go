@@ -0,0 +1,74 @@
+package lineedit
+
+import (
+	"fmt"
+	"io"
+)
+
+// Shows lines a screen at a time, height rows to a screen including the prompt below them
+// Space, f or Page Down shows the next screen, Enter, j or Down the next line,
+// b or Page Up the previous screen, and q, Ctrl-C or Ctrl-D stops
+// Returns once the last line is shown or the user stops
+// All lines are written at once when the editor is not interactive or they fit on one screen
+func (e *Editor) Page(lines []string, height int) error {
+	if !e.interactive || len(lines) < height {
+		return e.writeLines(lines)
+	}
+	restore, err := makeRaw(e.fd)
+	if err != nil {
+		return e.writeLines(lines)
+	}
+	defer restore()
+	return e.page(lines, height)
+}
+
+// Writes each line followed by a line ending
+func (e *Editor) writeLines(lines []string) error {
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(e.out, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Pages through lines with the terminal already in raw mode
+func (e *Editor) page(lines []string, height int) error {
+	rows := max(1, height-1)
+	shown := min(rows, len(lines))
+	if err := e.writeLines(lines[:shown]); err != nil {
+		return err
+	}
+	for shown < len(lines) {
+		fmt.Fprintf(e.out, "\x1b[7m--More-- (%d%%)\x1b[0m", shown*100/len(lines))
+		k, err := e.readKey()
+		fmt.Fprint(e.out, "\r\x1b[K")
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		from, to := shown, shown
+		switch {
+		case k.code == keyEnter || k.code == keyDown || k.r == 'j':
+			to = shown + 1
+		case k.code == keyPageDown || k.r == ' ' || k.r == 'f':
+			to = shown + rows
+		case k.code == keyPageUp || k.r == 'b':
+			// Redraw the screen before the one shown
+			from = max(0, shown-2*rows)
+			to = from + rows
+		case k.code == keyCtrlC || k.code == keyCtrlD || k.r == 'q' || k.r == 'Q':
+			return nil
+		default:
+			continue
+		}
+		to = min(to, len(lines))
+		if err := e.writeLines(lines[from:to]); err != nil {
+			return err
+		}
+		shown = to
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package lineedit
+
+import "errors"
+
+// Names ReadKey gives keys that are not printable characters
+var keyNames = map[keyCode]string{
+	keyEnter:     "enter",
+	keyTab:       "tab",
+	keyBackspace: "backspace",
+	keyDelete:    "delete",
+	keyLeft:      "left",
+	keyRight:     "right",
+	keyUp:        "up",
+	keyDown:      "down",
+	keyHome:      "home",
+	keyEnd:       "end",
+	keyPageUp:    "pgup",
+	keyPageDown:  "pgdown",
+	keyCancel:    "ctrl-g",
+	keyClear:     "ctrl-l",
+	keyCtrlC:     "ctrl-c",
+	keyCtrlD:     "ctrl-d",
+}
+
+// Puts the terminal into raw mode for a program reading keys with ReadKey
+// Returns a function restoring the previous settings
+// Returns an error if the editor is not reading from a terminal
+func (e *Editor) Raw() (func(), error) {
+	if !e.interactive {
+		return nil, errors.New("not a terminal")
+	}
+	return makeRaw(e.fd)
+}
+
+// Reads the next key and returns the character typed, or a name such as "up", "enter" or "ctrl-c"
+// Control keys are named for the editing action readline binds them to, so Ctrl-P reads as "up"
+// Keys without a name read as ""
+func (e *Editor) ReadKey() (string, error) {
+	k, err := e.readKey()
+	if err != nil {
+		return "", err
+	}
+	if k.code == keyRune {
+		return string(k.r), nil
+	}
+	return keyNames[k.code], nil
+}
@@ -0,0 +1,26 @@
+//go:build !linux && !darwin
+
+package lineedit
+
+import "errors"
+
+// Raw mode is not supported on this platform, so lines are read without editing
+func IsTerminal(fd int) bool {
+	return false
+}
+
+func Width(fd int) int {
+	return 0
+}
+
+func Height(fd int) int {
+	return 0
+}
+
+func makeRaw(fd int) (func(), error) {
+	return nil, errors.New("raw terminal mode is not supported on this platform")
+}
+
+func SaveState(fd int) (func(), error) {
+	return nil, errors.New("terminal state is not supported on this platform")
+}
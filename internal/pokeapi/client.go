@@ -0,0 +1,44 @@
+package pokeapi
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// Makes the API requests of one session
+// Each client keeps its own place in the list of location areas, its own stale indicator and its own trace
+// The zero value sends requests through http.DefaultClient and never serves stale entries
+// A Client must not be copied after first use
+type Client struct {
+	// Sends the requests; http.DefaultClient when nil
+	HTTP *http.Client
+	// When set, an expired cache entry inside the cache's stale window is returned
+	// immediately and refreshed from the API in the background
+	StaleWhileRevalidate bool
+	// When set, an expired cache entry inside the cache's stale window is returned
+	// if the API request fails
+	StaleIfError bool
+	// Page of location areas last returned by GetAreaLocation
+	mapArea PokemonMapArea
+	// Set whenever a stale entry is served, until ResetStale is called
+	servedStale atomic.Bool
+	// Trace in progress, if any
+	trace atomic.Pointer[trace]
+}
+
+// Client used by the package functions
+var defaultClient = &Client{}
+
+// Returns the HTTP client requests are sent through
+func (cl *Client) http() *http.Client {
+	if cl.HTTP == nil {
+		return http.DefaultClient
+	}
+	return cl.HTTP
+}
+
+// Reports whether the page last returned by GetAreaLocation is the end of the list
+// Going forward from the last page starts over at the first one
+func (cl *Client) LastPage() bool {
+	return cl.mapArea.Results != nil && cl.mapArea.Next == ""
+}
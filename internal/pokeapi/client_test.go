@@ -0,0 +1,56 @@
+package pokeapi
+
+import (
+	"internal/pokecache"
+	"math/rand"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestClientsKeepTheirOwnPlace(t *testing.T) {
+	fake := &fakeTransport{responses: map[string]string{
+		api + "location-area/?limit=20":           `{"next":"` + api + `location-area/?offset=20&limit=20","results":[{"name":"a"}]}`,
+		api + "location-area/?offset=20&limit=20": `{"previous":"` + api + `location-area/?limit=20","results":[{"name":"b"}]}`,
+	}, requests: make(map[string]int)}
+	cache := pokecache.NewCache(time.Minute)
+	defer cache.Close()
+	first, second := &Client{HTTP: &http.Client{Transport: fake}}, &Client{HTTP: &http.Client{Transport: fake}}
+
+	first.GetAreaLocation(1, cache)
+	if areas, err := first.GetAreaLocation(1, cache); err != nil || !reflect.DeepEqual(areas, []string{"b"}) {
+		t.Errorf("second page of the first client = %q, %v", areas, err)
+	}
+	if areas, err := second.GetAreaLocation(1, cache); err != nil || !reflect.DeepEqual(areas, []string{"a"}) {
+		t.Errorf("first page of the second client = %q, %v", areas, err)
+	}
+	if fake.requests[api+"location-area/?limit=20"] != 1 {
+		t.Errorf("requests = %v, want the first page requested once through the clients' transport", fake.requests)
+	}
+}
+
+func TestCatchRollsFromRand(t *testing.T) {
+	fake := &fakeTransport{responses: map[string]string{
+		api + "pokemon-species/mew": `{"capture_rate":45}`,
+		api + "pokemon/mew":         `{"name":"mew"}`,
+	}, requests: make(map[string]int)}
+	cl := &Client{HTTP: &http.Client{Transport: fake}}
+	catches := func() []bool {
+		cache := pokecache.NewCache(time.Minute)
+		defer cache.Close()
+		rng := rand.New(rand.NewSource(7))
+		var caught []bool
+		for i := 0; i < 8; i++ {
+			ok, err := cl.Catch("mew", rng, cache, pokecache.NewCache(0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			caught = append(caught, ok)
+		}
+		return caught
+	}
+	if first, second := catches(), catches(); !reflect.DeepEqual(first, second) {
+		t.Errorf("catches with the same seed differ: %v and %v", first, second)
+	}
+}
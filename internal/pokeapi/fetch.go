@@ -0,0 +1,145 @@
+package pokeapi
+
+import (
+	"errors"
+	"fmt"
+	"internal/pokecache"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Records cache use, requests and catch rolls
+// Requests and stale responses are logged at info level, cache hits and catch rolls at debug level
+// Discards everything until replaced
+var Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// Returned when the API has no resource at the requested URL
+type NotFoundError struct {
+	URL string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s was not found", strings.TrimPrefix(e.URL, BaseURL))
+}
+
+// Returned when the API answers with a status other than 2xx or 404
+type StatusError struct {
+	URL  string
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("response failed with status code: %d", e.Code)
+}
+
+// Reports whether the client served any stale cache entry since the last ResetStale
+func (cl *Client) ServedStale() bool {
+	return cl.servedStale.Load()
+}
+
+// Clears the stale indicator, typically before running a command
+func (cl *Client) ResetStale() {
+	cl.servedStale.Store(false)
+}
+
+// Function to retrieve the body of an API request
+// Takes in the request URL and an initialized cache map
+// Returns a fresh cached body if present, otherwise requests the API and caches the result
+// Expired entries in the stale window are served according to the client's StaleWhileRevalidate and StaleIfError
+// URLs recently found missing fail with a NotFoundError without a request
+// Returns nothing and the error if the request fails and no stale entry can be used
+// Each call is recorded in the trace started by StartTrace, if any
+func (cl *Client) fetchResource(httpRequest string, c *pokecache.Cache) (body []byte, err error) {
+	event := TraceEvent{URL: httpRequest, Start: time.Now()}
+	if trace := cl.trace.Load(); trace != nil {
+		defer func() { trace.add(&event, body, err) }()
+	}
+	if c.Missing(httpRequest) {
+		event.Source = "negative"
+		return nil, &NotFoundError{httpRequest}
+	}
+	val, tier := c.LookupTier(httpRequest)
+	if tier == pokecache.TierMemory || tier == pokecache.TierDisk {
+		Logger.Debug("cache hit", "url", httpRequest, "tier", tier)
+		event.Source = tier.String()
+		return val, nil
+	}
+	found := tier == pokecache.TierStale
+	if found && cl.StaleWhileRevalidate {
+		Logger.Info("serving stale response, refreshing in background", "url", httpRequest)
+		event.Source = "stale"
+		cl.servedStale.Store(true)
+		cl.refreshResource(httpRequest, c)
+		return val, nil
+	}
+	Logger.Debug("cache miss", "url", httpRequest)
+	event.Source = "network"
+	body, err = cl.fillResource(httpRequest, c)
+	if err != nil {
+		if found && cl.StaleIfError {
+			Logger.Info("serving stale response after failed request", "url", httpRequest, "err", err)
+			event.Source, event.Error = "stale", err.Error()
+			cl.servedStale.Store(true)
+			return val, nil
+		}
+		return nil, err
+	}
+	return body, nil
+}
+
+// Requests httpRequest in the background and stores the result in the cache
+// Repeated refreshes of a URL share one request until it completes, and the cache's Close waits for it
+// Failures leave the stale entry in place
+func (cl *Client) refreshResource(httpRequest string, c *pokecache.Cache) {
+	c.Refresh(httpRequest, cl.fetcher(httpRequest, c))
+}
+
+// Requests httpRequest and adds the result to the cache, sharing the request with concurrent callers
+func (cl *Client) fillResource(httpRequest string, c *pokecache.Cache) ([]byte, error) {
+	return c.Fill(httpRequest, cl.fetcher(httpRequest, c))
+}
+
+// Returns the fetch used to fill the cache with httpRequest
+// A NotFoundError is recorded in the cache so repeated requests for the URL fail without the network
+func (cl *Client) fetcher(httpRequest string, c *pokecache.Cache) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		body, err := cl.requestResource(httpRequest)
+		var notFound *NotFoundError
+		if errors.As(err, &notFound) {
+			c.AddMissing(httpRequest)
+		}
+		return body, err
+	}
+}
+
+// Performs the HTML request to the API, waiting first if SetRateLimit is in effect
+// Returns the Body of the response if successful
+// Returns a NotFoundError for a 404 response
+// Returns an error if the request fails or the response status is not 2xx
+func (cl *Client) requestResource(httpRequest string) ([]byte, error) {
+	if limiter := upstreamLimiter.Load(); limiter != nil {
+		limiter.wait()
+	}
+	start := time.Now()
+	res, err := cl.http().Get(httpRequest)
+	if err != nil {
+		Logger.Info("request failed", "url", httpRequest, "err", err)
+		return nil, err
+	}
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	Logger.Info("request", "url", httpRequest, "status", res.StatusCode, "bytes", len(body), "duration", time.Since(start))
+	if res.StatusCode == http.StatusNotFound {
+		return nil, &NotFoundError{httpRequest}
+	}
+	if res.StatusCode > 299 {
+		return nil, &StatusError{httpRequest, res.StatusCode}
+	}
+	return body, nil
+}
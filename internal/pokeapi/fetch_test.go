@@ -0,0 +1,125 @@
+package pokeapi
+
+import (
+	"errors"
+	"internal/pokecache"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Clock whose time only moves when the test says so, with tickers that never tick
+type stoppedClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *stoppedClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *stoppedClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func (c *stoppedClock) NewTicker(d time.Duration) pokecache.Ticker {
+	return stoppedTicker{}
+}
+
+type stoppedTicker struct{}
+
+func (stoppedTicker) Chan() <-chan time.Time { return nil }
+func (stoppedTicker) Stop()                  {}
+
+// Returns a cache with a one minute interval and stale window holding url's response, now expired
+func staleCache(t *testing.T, url string, body string) *pokecache.Cache {
+	t.Helper()
+	clock := &stoppedClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	cache := pokecache.NewCache(time.Minute, pokecache.WithStale(time.Minute), pokecache.WithClock(clock))
+	cache.Add(url, []byte(body))
+	clock.Advance(90 * time.Second)
+	return cache
+}
+
+func TestFetchRemembersNotFound(t *testing.T) {
+	fake := useFakeAPI(t, map[string]string{})
+	cache := pokecache.NewCache(time.Minute, pokecache.WithNegativeTTL(time.Minute))
+	defer cache.Close()
+
+	url := api + "location-area/mt-moon"
+	for i := 0; i < 3; i++ {
+		_, err := defaultClient.fetchResource(url, cache)
+		var notFound *NotFoundError
+		if !errors.As(err, &notFound) || notFound.URL != url {
+			t.Fatalf("fetchResource = %v, want NotFoundError for %s", err, url)
+		}
+	}
+	if fake.requests[url] != 1 {
+		t.Fatalf("%s requested %d times, want 1", url, fake.requests[url])
+	}
+	if stats := cache.Stats(); stats.NegativeHits != 2 {
+		t.Fatalf("Stats().NegativeHits = %d, want 2", stats.NegativeHits)
+	}
+}
+
+func TestNotFoundErrorMessage(t *testing.T) {
+	err := &NotFoundError{api + "location-area/mt-moon"}
+	if err.Error() != "location-area/mt-moon was not found" {
+		t.Fatalf("Error() = %q", err.Error())
+	}
+}
+
+func TestServeStaleWhileRevalidate(t *testing.T) {
+	cl := &Client{StaleWhileRevalidate: true}
+	url := api + "pokemon/pidgey"
+	fake := useFakeAPI(t, map[string]string{url: "new"})
+	cache := staleCache(t, url, "old")
+
+	for i := 0; i < 3; i++ {
+		body, err := cl.fetchResource(url, cache)
+		if err != nil || string(body) != "old" && string(body) != "new" {
+			t.Fatalf("fetchResource = %q, %v, want the stale or refreshed body", body, err)
+		}
+	}
+	if !cl.ServedStale() || defaultClient.ServedStale() {
+		t.Fatalf("ServedStale() = %v, default client %v after serving a stale entry, want only the client's set", cl.ServedStale(), defaultClient.ServedStale())
+	}
+	// Close waits for the background refresh
+	cache.Close()
+	if fake.requests[url] != 1 {
+		t.Fatalf("%s requested %d times, want 1", url, fake.requests[url])
+	}
+	if val, ok := cache.Get(url); !ok || string(val) != "new" {
+		t.Fatalf(`Get after refresh = %q, %v, want "new", true`, val, ok)
+	}
+}
+
+func TestServeStaleIfError(t *testing.T) {
+	url := api + "pokemon/pidgey"
+	useFakeAPI(t, map[string]string{})
+
+	cache := staleCache(t, url, "old")
+	defer cache.Close()
+	if _, err := defaultClient.fetchResource(url, cache); err == nil {
+		t.Fatalf("fetchResource without StaleIfError = nil error, want the request failure")
+	}
+
+	cl := &Client{StaleIfError: true}
+	cache = staleCache(t, url, "old")
+	defer cache.Close()
+	body, err := cl.fetchResource(url, cache)
+	if err != nil || string(body) != "old" {
+		t.Fatalf(`fetchResource = %q, %v, want "old", nil`, body, err)
+	}
+	if !cl.ServedStale() {
+		t.Fatalf("ServedStale() = false after serving a stale entry")
+	}
+	cl.ResetStale()
+	if cl.ServedStale() {
+		t.Fatalf("ServedStale() = true after ResetStale")
+	}
+}
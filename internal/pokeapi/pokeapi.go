@@ -1,14 +1,14 @@
 package pokeapi
 
 import (
-	"encoding/json"
+	"bytes"
 	"fmt"
+	"image"
+	"image/png"
 	"internal/pokecache"
-	"io"
-	"log"
 	"math"
 	"math/rand"
-	"net/http"
+	"strings"
 )
 
 // Struct defining how the reponse data for a Map Area should be interpreted
@@ -252,263 +252,300 @@ type PokemonDetailedInformation struct {
 	Weight int `json:"weight"`
 }
 
-var MapAreaResults PokemonMapArea
 var EncounterResults PokemonInArea
 var PokemonSpeciesInformation PokemonSpecies
 var PokemonInspectionInformation PokemonDetailedInformation
 
+// Returns the API request URL for the pokemon resource of the given name
+func pokemonURL(pokemon string) string {
+	return apiURL("pokemon/%s", pokemon)
+}
+
 // Function to request data from the Pokdemon Database API on a map area
 // Takes in an integer defining the direction (positive is forward, negative is backward)
 // Takes in an initialized cache map
 // Will look for the request first in the map and will return the cached value if found
 // Otherwise, will use the formatted request in HTML request to API and return the value
-// Returns the Body of the request from the cache or HTML message if successful
+// Returns the names of the areas from the cache or HTML message if successful
 // Returns nothing and the error if both the cache and HTML request fail
+// Pages are counted from the default client's place in the list
 func GetAreaLocation(dir int, c *pokecache.Cache) ([]string, error) {
+	return defaultClient.GetAreaLocation(dir, c)
+}
+
+// Returns the names of the next page of location areas for a dir of 1, or the previous one for -1
+// Pages are counted from the client's place in the list, which moves to the page returned
+func (cl *Client) GetAreaLocation(dir int, c *pokecache.Cache) ([]string, error) {
 	//The request will return a list of locations, this is storage
 	retrievedAreas := make([]string, 0)
 	var httpRequest string
 	//1 is going forward
 	if dir == 1 {
-		if MapAreaResults.Next != "" {
-			fmt.Println("Next")
-			httpRequest = MapAreaResults.Next
+		if cl.mapArea.Next != "" {
+			httpRequest = cl.mapArea.Next
 		} else {
-			httpRequest = "https://pokeapi.co/api/v2/location-area/?limit=20"
+			httpRequest = apiURL("location-area/?limit=%d", PageSize)
 		}
 	} else {
-		if MapAreaResults.Previous != "" {
-			httpRequest = MapAreaResults.Previous
+		if cl.mapArea.Previous != "" {
+			httpRequest = cl.mapArea.Previous
 		} else {
 			return retrievedAreas, fmt.Errorf("Cannot retrieve previous; at list beginning\n")
 		}
 	}
-	//Check the cache for the request
-	val, found := c.Get(httpRequest)
-	if !found {
-		fmt.Println("Not found in cache, retrieving")
-		res, error := http.Get(httpRequest)
-		if error != nil {
-			log.Fatal(error)
-			return retrievedAreas, error
-		}
-		body, err := io.ReadAll(res.Body)
-		res.Body.Close()
-		if res.StatusCode > 299 {
-			log.Fatalf("Response failed with status code: %d and \nbody: %s\n", res.StatusCode, body)
-		}
-		if err != nil {
-			log.Fatal(err)
-		}
-		//Add the new request to the cache
-		c.Add(httpRequest, body)
-		marshalErr := json.Unmarshal(body, &MapAreaResults)
-
-		if marshalErr != nil {
-			fmt.Println(marshalErr)
-		}
-
-		//Format the list with the retrieved values
-		for i := 0; i < 20; i++ {
-			retrievedAreas = append(retrievedAreas, MapAreaResults.Results[i].Name)
-		}
-	} else {
-		fmt.Println("Found in cache, retreiving local")
-		marshalErr := json.Unmarshal(val, &MapAreaResults)
-
-		if marshalErr != nil {
-			fmt.Println(marshalErr)
-		}
+	body, err := cl.fetchResource(httpRequest, c)
+	if err != nil {
+		return retrievedAreas, err
+	}
+	cl.mapArea, err = pokecache.TypedFor[PokemonMapArea](c).Decode(httpRequest, body)
+	if err != nil {
+		return retrievedAreas, err
+	}
 
-		for i := 0; i < 20; i++ {
-			retrievedAreas = append(retrievedAreas, MapAreaResults.Results[i].Name)
-		}
+	//Format the list with the retrieved values
+	for _, area := range cl.mapArea.Results {
+		retrievedAreas = append(retrievedAreas, area.Name)
 	}
 
 	return retrievedAreas, nil
 }
 
+// Returns the names of the pokemon that can be encountered in the area
 func GetPokemonInArea(area string, c *pokecache.Cache) ([]string, error) {
-	var httpRequest = fmt.Sprintf("https://pokeapi.co/api/v2/location-area/%s", area)
-	retreivedEncounters := make([]string, 0)
-	val, found := c.Get(httpRequest)
-	if !found {
-		fmt.Println("Not found in cache, retrieving")
-		res, error := http.Get(httpRequest)
-		if error != nil {
-			log.Fatal(error)
-			return retreivedEncounters, error
-		}
-		body, err := io.ReadAll(res.Body)
-		res.Body.Close()
-		if res.StatusCode > 299 {
-			log.Fatalf("Response failed with status code: %d and \nbody: %s\n", res.StatusCode, body)
-		}
-		if err != nil {
-			log.Fatal(err)
-		}
-		//Add the new request to the cache
-		c.Add(httpRequest, body)
-		marshalErr := json.Unmarshal(body, &EncounterResults)
+	encounters, err := Explore(area, c)
+	return encounters.Pokemon, err
+}
 
-		if marshalErr != nil {
-			fmt.Println(marshalErr)
-		}
+// Pokemon that can be encountered in a location area
+type AreaEncounters struct {
+	// Name of the area as used in requests
+	Name string
+	// Name of the area in Language, or Name if it has none
+	DisplayName string
+	Pokemon     []string
+}
 
-		for _, encounters := range EncounterResults.Encounters {
-			retreivedEncounters = append(retreivedEncounters, encounters.Pokemon.Pokemon_Name)
-		}
+// Returns the pokemon that can be encountered in the area
+// Only pokemon found in GameVersion are listed when it is set
+func Explore(area string, c *pokecache.Cache) (AreaEncounters, error) {
+	return defaultClient.Explore(area, c)
+}
 
-	} else {
-		fmt.Println("Found in cache, retreiving local")
-		marshalErr := json.Unmarshal(val, &EncounterResults)
+// Returns the pokemon that can be encountered in the area
+// Only pokemon found in GameVersion are listed when it is set
+func (cl *Client) Explore(area string, c *pokecache.Cache) (AreaEncounters, error) {
+	var httpRequest = apiURL("location-area/%s", area)
+	retreivedEncounters := AreaEncounters{Name: area, DisplayName: area, Pokemon: make([]string, 0)}
+	body, err := cl.fetchResource(httpRequest, c)
+	if err != nil {
+		return retreivedEncounters, err
+	}
+	EncounterResults, err = pokecache.TypedFor[PokemonInArea](c).Decode(httpRequest, body)
+	if err != nil {
+		return retreivedEncounters, err
+	}
 
-		if marshalErr != nil {
-			fmt.Println(marshalErr)
+	for _, name := range EncounterResults.Names {
+		if name.Language.Language_Name == Language && name.Name != "" {
+			retreivedEncounters.DisplayName = name.Name
 		}
-
-		for _, encounters := range EncounterResults.Encounters {
-			retreivedEncounters = append(retreivedEncounters, encounters.Pokemon.Pokemon_Name)
+	}
+	for _, encounters := range EncounterResults.Encounters {
+		found := GameVersion == ""
+		for _, details := range encounters.Pokemon_Version_Details {
+			found = found || details.Version.Version_Name == GameVersion
+		}
+		if found {
+			retreivedEncounters.Pokemon = append(retreivedEncounters.Pokemon, encounters.Pokemon.Pokemon_Name)
 		}
 	}
 
 	return retreivedEncounters, nil
-
 }
 
 // Catch Pokemon Function
 // Arguments:
 // Pokemon (string) : The pokemon to be caught as a character string
 // C (PokeCache) : Cache storing all encountered pokemon in the current location
+// Pokedex (PokeCache) : Cache without expiry storing caught pokemon by name
 // Returns:
 // Success Message (string) : A processed string indicating whether the pokemon was caught or escaped
 // error (error) : An error occured and returning from the function
-func CatchPokemon(pokemon string, c *pokecache.Cache, pokedex map[string][]byte) (string, error) {
-	var httpRequest = fmt.Sprintf("https://pokeapi.co/api/v2/pokemon-species/%s", pokemon)
-	val, found := c.Get(httpRequest)
-	if !found {
-		fmt.Println("Not found in cache, retrieving")
-		res, error := http.Get(httpRequest)
-		if error != nil {
-			log.Fatal(error)
-			return "Could not find pokemon", error
-		}
-		body, err := io.ReadAll(res.Body)
-		res.Body.Close()
-		if res.StatusCode > 299 {
-			log.Fatalf("Response failed with status code: %d and \nbody: %s\n", res.StatusCode, body)
-		}
-		if err != nil {
-			log.Fatal(err)
-		}
-		//Add the new request to the cache
-		c.Add(httpRequest, body)
-		marshalErr := json.Unmarshal(body, &PokemonSpeciesInformation)
-
-		if marshalErr != nil {
-			fmt.Println(marshalErr)
-		}
+func CatchPokemon(pokemon string, c *pokecache.Cache, pokedex *pokecache.Cache) (string, error) {
+	captured, err := Catch(pokemon, c, pokedex)
+	if err != nil {
+		return "Could not find pokemon", err
+	}
+	if captured {
+		return fmt.Sprintf("%s has been captured!", pokemon), nil
+	}
+	return fmt.Sprintf("%s has escaped!", pokemon), nil
+}
 
-	} else {
-		fmt.Println("Found in cache, retreiving local")
-		marshalErr := json.Unmarshal(val, &PokemonSpeciesInformation)
+// Throws a pokeball at the pokemon, adding it to the pokedex if it is caught
+// The chance of a catch follows the species' capture rate
+// Returns whether the pokemon was caught
+func Catch(pokemon string, c *pokecache.Cache, pokedex *pokecache.Cache) (bool, error) {
+	return defaultClient.Catch(pokemon, nil, c, pokedex)
+}
 
-		if marshalErr != nil {
-			fmt.Println(marshalErr)
-		}
+// Throws a pokeball at the pokemon, adding it to the pokedex if it is caught
+// The rolls come from rng, or the source set by SetSeed when it is nil
+// Returns whether the pokemon was caught
+func (cl *Client) Catch(pokemon string, rng *rand.Rand, c *pokecache.Cache, pokedex *pokecache.Cache) (bool, error) {
+	var httpRequest = apiURL("pokemon-species/%s", pokemon)
+	body, err := cl.fetchResource(httpRequest, c)
+	if err != nil {
+		return false, err
+	}
+	PokemonSpeciesInformation, err = pokecache.TypedFor[PokemonSpecies](c).Decode(httpRequest, body)
+	if err != nil {
+		return false, err
 	}
 	a := float64(PokemonSpeciesInformation.Capture_rate) * 1.5
 	shake_probability := float64(1048560) / math.Sqrt(math.Sqrt(float64(16711680)/a))
 	captured := true
-	fmt.Printf("%f is the modified capture rate and %f is the shake_probability", a, shake_probability)
+	Logger.Debug("catch odds", "pokemon", pokemon, "modified_capture_rate", a, "shake_probability", shake_probability)
 	for i := 0; i < 4; i++ {
-		random_number := rand.Float64() * 65535.0
-		fmt.Printf("Random Generation: %f\n", random_number)
+		roll := randomFloat
+		if rng != nil {
+			roll = rng.Float64
+		}
+		random_number := roll() * 65535.0
+		Logger.Debug("shake roll", "pokemon", pokemon, "shake", i+1, "roll", random_number)
 		if random_number > shake_probability {
 			captured = false
 			break
 		}
 	}
-	var captured_string string
-	if captured {
-		captured_string = fmt.Sprintf("%s has been captured!", pokemon)
+	if !captured {
+		return false, nil
+	}
+	httpRequest = pokemonURL(pokemon)
+	body, err = cl.fetchResource(httpRequest, c)
+	if err != nil {
+		return false, err
+	}
+	PokemonInspectionInformation, err = pokecache.TypedFor[PokemonDetailedInformation](c).Decode(httpRequest, body)
+	if err != nil {
+		return false, err
+	}
+	pokedex.Add(pokemon, body)
+	return true, nil
+}
 
-		var httpRequest = fmt.Sprintf("https://pokeapi.co/api/v2/pokemon/%s", pokemon)
-		val, found := c.Get(httpRequest)
-		if !found {
-			fmt.Println("Not found in cache, retrieving")
-			res, error := http.Get(httpRequest)
-			if error != nil {
-				log.Fatal(error)
-				return "Could not find pokemon", error
-			}
-			body, err := io.ReadAll(res.Body)
-			res.Body.Close()
-			if res.StatusCode > 299 {
-				log.Fatalf("Response failed with status code: %d and \nbody: %s\n", res.StatusCode, body)
-			}
-			if err != nil {
-				log.Fatal(err)
-			}
-			//Add the new request to the cache
-			pokedex[pokemon] = body
-			marshalErr := json.Unmarshal(body, &PokemonInspectionInformation)
+// Returns the details of any pokemon from the API, caught or not
+func Pokemon(pokemon string, c *pokecache.Cache) (PokemonDetailedInformation, error) {
+	return defaultClient.Pokemon(pokemon, c)
+}
 
-			if marshalErr != nil {
-				fmt.Println(marshalErr)
-			}
+// Returns the details of any pokemon from the API, caught or not
+func (cl *Client) Pokemon(pokemon string, c *pokecache.Cache) (PokemonDetailedInformation, error) {
+	httpRequest := pokemonURL(pokemon)
+	body, err := cl.fetchResource(httpRequest, c)
+	if err != nil {
+		return PokemonDetailedInformation{}, err
+	}
+	return pokecache.TypedFor[PokemonDetailedInformation](c).Decode(httpRequest, body)
+}
 
-		} else {
-			fmt.Println("Found in cache, retreiving local")
-			marshalErr := json.Unmarshal(val, &PokemonInspectionInformation)
+// Error for inspecting a pokemon that is not in the pokedex
+type NotCaughtError struct {
+	Name string
+}
 
-			if marshalErr != nil {
-				fmt.Println(marshalErr)
-			}
-		}
-	} else {
-		captured_string = fmt.Sprintf("%s has escaped!", pokemon)
-	}
-	return captured_string, nil
+func (e *NotCaughtError) Error() string {
+	return fmt.Sprintf("You aint caught %s yet", e.Name)
 }
 
-func InspectPokemon(pokemon string, p map[string][]byte) (string, error) {
+// Returns the details of a caught pokemon, formatted for display
+// Returns a NotCaughtError if the pokemon is not in the pokedex
+func InspectPokemon(pokemon string, p *pokecache.Cache) (string, error) {
 	var information_string string
-	val, found := p[pokemon]
+	info, err := Inspect(pokemon, p)
+	if err != nil {
+		return information_string, err
+	}
+	information_string = fmt.Sprintf("Name: %s\nHeight: %d\nWeight: %d\nStats:\n\t-hp: %d\n\t-attack: %d\n\t-defense: %d\n\t-special-attack: %d\n\t-special-defense: %d\n\t-speed: %d\n",
+		info.Name, info.Height, info.Weight,
+		info.Stats[0].Base_stat, info.Stats[1].Base_stat,
+		info.Stats[2].Base_stat, info.Stats[3].Base_stat,
+		info.Stats[4].Base_stat, info.Stats[5].Base_stat)
+	information_string += fmt.Sprintf("Types: \n\t-%s", info.Types[0].Type.Name)
+	if len(info.Types) > 1 {
+		information_string += fmt.Sprintf("\n\t-%s", info.Types[1].Type.Name)
+	}
+	return information_string, nil
+}
+
+// Returns the details of a caught pokemon
+// Returns a NotCaughtError if the pokemon is not in the pokedex
+func Inspect(pokemon string, p *pokecache.Cache) (PokemonDetailedInformation, error) {
+	info, found, err := pokecache.TypedFor[PokemonDetailedInformation](p).DecodeFrom(p, pokemon)
+	if err != nil {
+		return info, err
+	}
 	if !found {
-		fmt.Println("You aint caught that yet")
+		return info, &NotCaughtError{pokemon}
+	}
+	PokemonInspectionInformation = info
+	return info, nil
+}
 
-	} else {
-		fmt.Println("Found in cache, retreiving local")
-		marshalErr := json.Unmarshal(val, &PokemonInspectionInformation)
+// Returns the pokemon's front_default sprite, downloaded through the cache c
+// Returns an error if the pokemon has no sprite or it is not a PNG
+func Sprite(info PokemonDetailedInformation, c *pokecache.Cache) (image.Image, error) {
+	return defaultClient.Sprite(info, c)
+}
 
-		if marshalErr != nil {
-			fmt.Println(marshalErr)
-		}
+// Returns the pokemon's front_default sprite, downloaded through the cache c
+// Returns an error if the pokemon has no sprite or it is not a PNG
+func (cl *Client) Sprite(info PokemonDetailedInformation, c *pokecache.Cache) (image.Image, error) {
+	url, _ := info.Sprites["front_default"].(string)
+	if url == "" {
+		return nil, fmt.Errorf("%s has no sprite", info.Name)
+	}
+	data, err := cl.fetchResource(url, c)
+	if err != nil {
+		return nil, err
+	}
+	return png.Decode(bytes.NewReader(data))
+}
 
-		information_string = fmt.Sprintf("Name: %s\nHeight: %d\nWeight: %d\nStats:\n\t-hp: %d\n\t-attack: %d\n\t-defense: %d\n\t-special-attack: %d\n\t-special-defense: %d\n\t-speed: %d\n",
-			PokemonInspectionInformation.Name, PokemonInspectionInformation.Height, PokemonInspectionInformation.Weight,
-			PokemonInspectionInformation.Stats[0].Base_stat, PokemonInspectionInformation.Stats[1].Base_stat,
-			PokemonInspectionInformation.Stats[2].Base_stat, PokemonInspectionInformation.Stats[3].Base_stat,
-			PokemonInspectionInformation.Stats[4].Base_stat, PokemonInspectionInformation.Stats[5].Base_stat)
-		information_string += fmt.Sprintf("Types: \n\t-%s", PokemonInspectionInformation.Types[0].Type.Name)
-		if len(PokemonInspectionInformation.Types) > 1 {
-			information_string += fmt.Sprintf("\n\t-%s", PokemonInspectionInformation.Types[1].Type.Name)
-		}
+// Lists the names of every pokemon in the pokedex in sorted order
+// Decoded entries are reused between calls, so only newly caught pokemon are unmarshalled
+func ExplorePokedex(p *pokecache.Cache) (string, error) {
+	var pokedex_list strings.Builder
+	pokedex_list.WriteString("Your Pokemon: \n")
+	names, err := PokedexNames(p)
+	for _, name := range names {
+		fmt.Fprintf(&pokedex_list, "\t-%s\n", name)
 	}
-	return information_string, nil
+	return pokedex_list.String(), err
 }
 
-func ExplorePokedex(p map[string][]byte) (string, error) {
-    pokedex_list := "Your Pokemon: \n"
-	for _, pokemon_info := range p {
-		marshalErr := json.Unmarshal(pokemon_info, &PokemonInspectionInformation)
+// Returns the names of every pokemon in the pokedex in sorted order
+func PokedexNames(p *pokecache.Cache) ([]string, error) {
+	entries, err := PokedexEntries(p)
+	names := make([]string, 0, len(entries))
+	for _, info := range entries {
+		names = append(names, info.Name)
+	}
+	return names, err
+}
 
-		if marshalErr != nil {
-			fmt.Println(marshalErr)
+// Returns the details of every pokemon in the pokedex, sorted by name
+func PokedexEntries(p *pokecache.Cache) ([]PokemonDetailedInformation, error) {
+	entries := make([]PokemonDetailedInformation, 0)
+	for _, pokemon := range p.Keys() {
+		info, found, err := pokecache.TypedFor[PokemonDetailedInformation](p).DecodeFrom(p, pokemon)
+		if err != nil {
+			return entries, err
 		}
-
-		pokedex_list += fmt.Sprintf("\t-%s\n", PokemonInspectionInformation.Name)
+		if !found {
+			continue
+		}
+		entries = append(entries, info)
 	}
-	return pokedex_list, nil
+	return entries, nil
 }
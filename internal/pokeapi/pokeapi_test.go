@@ -0,0 +1,172 @@
+package pokeapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"internal/pokecache"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Builds a pokemon response body roughly the size of a real PokeAPI one
+func fakePokemonBody(name string) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, `{"name":%q,"height":4,"weight":60,"moves":[`, name)
+	for i := 0; i < 80; i++ {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, `{"move":{"name":"move-%d","url":"https://pokeapi.co/api/v2/move/%d/"}}`, i, i)
+	}
+	b.WriteString(`],"game_indices":[`)
+	for i := 0; i < 20; i++ {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, `{"game_index":%d,"version":{"name":"version-%d","url":"https://pokeapi.co/api/v2/version/%d/"}}`, i, i, i)
+	}
+	b.WriteString(`],"sprites":{`)
+	for i := 0; i < 30; i++ {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, `"sprite_%d":"https://raw.githubusercontent.com/PokeAPI/sprites/master/sprites/pokemon/%d.png"`, i, i)
+	}
+	b.WriteString(`},"stats":[`)
+	for i := 0; i < 6; i++ {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, `{"base_stat":%d,"effort":0,"stat":{"name":"stat-%d","url":""}}`, 40+i, i)
+	}
+	b.WriteString(`],"types":[{"slot":1,"type":{"name":"electric","url":""}}]}`)
+	return []byte(b.String())
+}
+
+func fakePokedex(size int) *pokecache.Cache {
+	pokedex := pokecache.NewCache(0, pokecache.WithCompression(1024))
+	for i := 0; i < size; i++ {
+		name := fmt.Sprintf("pokemon-%d", i)
+		pokedex.Add(name, fakePokemonBody(name))
+	}
+	return pokedex
+}
+
+func TestExplorePokedexListsNames(t *testing.T) {
+	pokedex := fakePokedex(3)
+	list, err := ExplorePokedex(pokedex)
+	if err != nil {
+		t.Fatalf("ExplorePokedex = %v, want nil error", err)
+	}
+	for _, name := range pokedex.Keys() {
+		if !strings.Contains(list, "\t-"+name+"\n") {
+			t.Errorf("ExplorePokedex output %q missing %s", list, name)
+		}
+	}
+}
+
+func TestInspectPokemon(t *testing.T) {
+	pokedex := fakePokedex(1)
+	info, err := InspectPokemon("pokemon-0", pokedex)
+	if err != nil || !strings.HasPrefix(info, "Name: pokemon-0\n") {
+		t.Errorf("InspectPokemon = %q, %v, want the pokemon's details", info, err)
+	}
+	_, err = InspectPokemon("mew", pokedex)
+	var notCaught *NotCaughtError
+	if !errors.As(err, &notCaught) || notCaught.Name != "mew" {
+		t.Errorf("InspectPokemon(mew) error = %v, want NotCaughtError", err)
+	}
+}
+
+// Listing the way ExplorePokedex did before decoded values were cached
+func BenchmarkExplorePokedexUnmarshal(b *testing.B) {
+	for _, size := range []int{100, 500} {
+		pokedex := fakePokedex(size)
+		b.Run(fmt.Sprintf("entries=%d", size), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				var list strings.Builder
+				for _, name := range pokedex.Keys() {
+					body, _ := pokedex.Get(name)
+					var info PokemonDetailedInformation
+					if err := json.Unmarshal(body, &info); err != nil {
+						b.Fatal(err)
+					}
+					list.WriteString(info.Name)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkExplorePokedex(b *testing.B) {
+	for _, size := range []int{100, 500} {
+		pokedex := fakePokedex(size)
+		b.Run(fmt.Sprintf("entries=%d", size), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := ExplorePokedex(pokedex); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestSpriteDownloadedThroughCache(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 3))
+	img.Set(1, 2, color.RGBA{R: 255, A: 255})
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, img); err != nil {
+		t.Fatal(err)
+	}
+	url := "https://raw.githubusercontent.com/PokeAPI/sprites/master/sprites/pokemon/25.png"
+	fake := useFakeAPI(t, map[string]string{url: encoded.String()})
+	cache := pokecache.NewCache(time.Minute)
+	defer cache.Close()
+
+	info := PokemonDetailedInformation{Name: "pikachu", Sprites: map[string]interface{}{"front_default": url, "back_default": nil}}
+	for i := 0; i < 2; i++ {
+		sprite, err := Sprite(info, cache)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sprite.Bounds().Dx() != 2 || sprite.Bounds().Dy() != 3 {
+			t.Fatalf("sprite bounds %v, want 2x3", sprite.Bounds())
+		}
+		if r, _, _, _ := sprite.At(1, 2).RGBA(); r != 0xffff {
+			t.Errorf("sprite pixel (1, 2) red = %#x, want 0xffff", r)
+		}
+	}
+	if fake.requests[url] != 1 {
+		t.Errorf("sprite requested %d times, want 1", fake.requests[url])
+	}
+
+	if _, err := Sprite(PokemonDetailedInformation{Name: "missingno"}, cache); err == nil || err.Error() != "missingno has no sprite" {
+		t.Errorf("Sprite without a front_default = %v, want no sprite error", err)
+	}
+}
+
+func TestPokemonNeedNotBeCaught(t *testing.T) {
+	useFakeAPI(t, map[string]string{api + "pokemon/mew": `{"name":"mew","id":151,"types":[{"slot":1,"type":{"name":"psychic"}}]}`})
+	cache := pokecache.NewCache(time.Minute)
+	defer cache.Close()
+
+	info, err := Pokemon("mew", cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Name != "mew" || info.Id != 151 || len(info.Types) != 1 || info.Types[0].Type.Name != "psychic" {
+		t.Errorf("Pokemon(mew) = %+v", info)
+	}
+	var notFound *NotFoundError
+	if _, err := Pokemon("missingno", cache); !errors.As(err, &notFound) {
+		t.Errorf("Pokemon(missingno) error = %v, want NotFoundError", err)
+	}
+}
@@ -0,0 +1,266 @@
+package pokeapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"internal/pokecache"
+	"sync"
+	"time"
+)
+
+// Counts reported to the progress callback while a prefetch runs
+// Total grows as listing resources reveal more resources to fetch
+type PrefetchProgress struct {
+	Done   int
+	Total  int
+	Cached int
+	Failed int
+}
+
+// A resource that could not be fetched during a prefetch
+type PrefetchFailure struct {
+	URL string
+	Err error
+}
+
+// Result of a prefetch
+// Interrupted is set if the context was cancelled before every resource was fetched
+type PrefetchSummary struct {
+	PrefetchProgress
+	Failures    []PrefetchFailure
+	Duration    time.Duration
+	Interrupted bool
+}
+
+// A resource to fetch and how to find the resources it lists
+// expand is nil for resources that list nothing further
+type prefetchTask struct {
+	url    string
+	expand func(body []byte) ([]prefetchTask, error)
+}
+
+// Shared state of a running prefetch
+type prefetcher struct {
+	cl       *Client
+	c        *pokecache.Cache
+	sem      chan struct{}
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	seen     map[string]bool
+	summary  PrefetchSummary
+	progress func(PrefetchProgress)
+}
+
+// Number of completed resources between flushes of the cache's persistent tier
+// Bounds how much work is repeated when an interrupted prefetch is resumed
+const prefetchFlushEvery = 25
+
+// Crawls every species, pokemon, location area and its encounters for a generation or region into the cache
+// Takes in the kind ("generation" or "region"), its name or id, an initialized cache map and the number of concurrent requests
+// Resources already in the cache are not requested again, so rerunning an interrupted prefetch resumes it
+// progress, if not nil, is called after each resource completes; calls are never concurrent
+// Returns a summary including every failed resource; the error is only set if the kind is unknown
+// Requests are made through the default client
+func Prefetch(ctx context.Context, kind string, name string, c *pokecache.Cache, workers int, progress func(PrefetchProgress)) (PrefetchSummary, error) {
+	return defaultClient.Prefetch(ctx, kind, name, c, workers, progress)
+}
+
+// Crawls a generation or region into the cache like the package's Prefetch, making requests through the client
+func (cl *Client) Prefetch(ctx context.Context, kind string, name string, c *pokecache.Cache, workers int, progress func(PrefetchProgress)) (PrefetchSummary, error) {
+	var root prefetchTask
+	switch kind {
+	case "generation":
+		root = generationTask(name)
+	case "region":
+		root = regionTask(name)
+	default:
+		return PrefetchSummary{}, fmt.Errorf("unknown prefetch kind %q; want generation or region", kind)
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	p := &prefetcher{
+		cl:       cl,
+		c:        c,
+		sem:      make(chan struct{}, workers),
+		seen:     make(map[string]bool),
+		progress: progress,
+	}
+	start := time.Now()
+	p.queue(ctx, []prefetchTask{root})
+	p.wg.Wait()
+	c.Flush()
+	p.summary.Duration = time.Since(start)
+	p.summary.Interrupted = ctx.Err() != nil
+	return p.summary, nil
+}
+
+// Starts fetching each task not already seen
+func (p *prefetcher) queue(ctx context.Context, tasks []prefetchTask) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, task := range tasks {
+		if p.seen[task.url] {
+			continue
+		}
+		p.seen[task.url] = true
+		p.summary.Total++
+		p.wg.Add(1)
+		go p.run(ctx, task)
+	}
+}
+
+// Fetches a task once a worker slot is free, then queues the resources it lists
+func (p *prefetcher) run(ctx context.Context, task prefetchTask) {
+	defer p.wg.Done()
+	if ctx.Err() != nil {
+		return
+	}
+	select {
+	case p.sem <- struct{}{}:
+	case <-ctx.Done():
+		return
+	}
+	body, cached, err := p.fetch(ctx, task.url)
+	<-p.sem
+	if err != nil && err == ctx.Err() {
+		return
+	}
+
+	var more []prefetchTask
+	if err == nil && task.expand != nil {
+		more, err = task.expand(body)
+	}
+	p.finish(task.url, cached, err)
+	if len(more) > 0 && ctx.Err() == nil {
+		p.queue(ctx, more)
+	}
+}
+
+// Returns the body for url from the cache, or requests it and adds it to the cache
+// Other processes prefetching into the same cache directory share the request
+// Reports whether the body was already cached
+func (p *prefetcher) fetch(ctx context.Context, url string) ([]byte, bool, error) {
+	if body, found := p.c.Get(url); found {
+		return body, true, nil
+	}
+	if p.c.Missing(url) {
+		return nil, false, &NotFoundError{url}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	body, err := p.cl.fillResource(url, p.c)
+	return body, false, err
+}
+
+// Records the outcome of a task, reports progress and periodically flushes the cache
+func (p *prefetcher) finish(url string, cached bool, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.summary.Done++
+	if cached {
+		p.summary.Cached++
+	}
+	if err != nil {
+		p.summary.Failed++
+		p.summary.Failures = append(p.summary.Failures, PrefetchFailure{url, err})
+	}
+	if p.summary.Done%prefetchFlushEvery == 0 {
+		p.c.Flush()
+	}
+	if p.progress != nil {
+		p.progress(p.summary.PrefetchProgress)
+	}
+}
+
+// Named resource reference as listed by the API
+type namedResource struct {
+	Name string `json:"name"`
+	Url  string `json:"url"`
+}
+
+func generationTask(name string) prefetchTask {
+	return prefetchTask{
+		url: apiURL("generation/%s", name),
+		expand: func(body []byte) ([]prefetchTask, error) {
+			var generation struct {
+				Main_region     namedResource   `json:"main_region"`
+				Pokemon_species []namedResource `json:"pokemon_species"`
+			}
+			if err := json.Unmarshal(body, &generation); err != nil {
+				return nil, err
+			}
+			tasks := []prefetchTask{regionTask(generation.Main_region.Name)}
+			for _, species := range generation.Pokemon_species {
+				tasks = append(tasks, pokemonTasks(species.Name)...)
+			}
+			return tasks, nil
+		},
+	}
+}
+
+func regionTask(name string) prefetchTask {
+	return prefetchTask{
+		url: apiURL("region/%s", name),
+		expand: func(body []byte) ([]prefetchTask, error) {
+			var region struct {
+				Locations []namedResource `json:"locations"`
+			}
+			if err := json.Unmarshal(body, &region); err != nil {
+				return nil, err
+			}
+			tasks := make([]prefetchTask, 0, len(region.Locations))
+			for _, location := range region.Locations {
+				tasks = append(tasks, locationTask(location.Name))
+			}
+			return tasks, nil
+		},
+	}
+}
+
+func locationTask(name string) prefetchTask {
+	return prefetchTask{
+		url: apiURL("location/%s", name),
+		expand: func(body []byte) ([]prefetchTask, error) {
+			var location struct {
+				Areas []namedResource `json:"areas"`
+			}
+			if err := json.Unmarshal(body, &location); err != nil {
+				return nil, err
+			}
+			tasks := make([]prefetchTask, 0, len(location.Areas))
+			for _, area := range location.Areas {
+				tasks = append(tasks, areaTask(area.Name))
+			}
+			return tasks, nil
+		},
+	}
+}
+
+// Location area as requested by GetPokemonInArea, listing the pokemon encountered there
+func areaTask(name string) prefetchTask {
+	return prefetchTask{
+		url: apiURL("location-area/%s", name),
+		expand: func(body []byte) ([]prefetchTask, error) {
+			var area PokemonInArea
+			if err := json.Unmarshal(body, &area); err != nil {
+				return nil, err
+			}
+			var tasks []prefetchTask
+			for _, encounter := range area.Encounters {
+				tasks = append(tasks, pokemonTasks(encounter.Pokemon.Pokemon_Name)...)
+			}
+			return tasks, nil
+		},
+	}
+}
+
+// Species and pokemon resources as requested by CatchPokemon
+func pokemonTasks(name string) []prefetchTask {
+	return []prefetchTask{
+		{url: apiURL("pokemon-species/%s", name)},
+		{url: pokemonURL(name)},
+	}
+}
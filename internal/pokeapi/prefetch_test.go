@@ -0,0 +1,153 @@
+package pokeapi
+
+import (
+	"context"
+	"internal/pokecache"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Serves canned API responses in place of the network
+type fakeTransport struct {
+	mu        sync.Mutex
+	responses map[string]string
+	requests  map[string]int
+}
+
+func (f *fakeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests[req.URL.String()]++
+	body, ok := f.responses[req.URL.String()]
+	status := http.StatusOK
+	if !ok {
+		status, body = http.StatusNotFound, "Not Found"
+	}
+	return &http.Response{
+		StatusCode: status,
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+// Routes requests made through http.DefaultClient to responses for the rest of the test
+func useFakeAPI(t *testing.T, responses map[string]string) *fakeTransport {
+	t.Helper()
+	fake := &fakeTransport{responses: responses, requests: make(map[string]int)}
+	old := http.DefaultClient.Transport
+	http.DefaultClient.Transport = fake
+	t.Cleanup(func() { http.DefaultClient.Transport = old })
+	return fake
+}
+
+const api = "https://pokeapi.co/api/v2/"
+
+var kantoResponses = map[string]string{
+	api + "generation/1":                   `{"main_region":{"name":"kanto"},"pokemon_species":[{"name":"bulbasaur"},{"name":"pidgey"}]}`,
+	api + "region/kanto":                   `{"locations":[{"name":"pallet-town"}]}`,
+	api + "location/pallet-town":           `{"areas":[{"name":"pallet-town-area"}]}`,
+	api + "location-area/pallet-town-area": `{"pokemon_encounters":[{"pokemon":{"name":"pidgey"}},{"pokemon":{"name":"rattata"}}]}`,
+	api + "pokemon-species/bulbasaur":      `{"name":"bulbasaur"}`,
+	api + "pokemon/bulbasaur":              `{"name":"bulbasaur"}`,
+	api + "pokemon-species/pidgey":         `{"name":"pidgey"}`,
+	api + "pokemon/pidgey":                 `{"name":"pidgey"}`,
+	api + "pokemon-species/rattata":        `{"name":"rattata"}`,
+}
+
+func TestPrefetchGeneration(t *testing.T) {
+	fake := useFakeAPI(t, kantoResponses)
+	cache := pokecache.NewCache(time.Hour)
+	defer cache.Close()
+
+	var calls int
+	summary, err := Prefetch(context.Background(), "generation", "1", cache, 3, func(PrefetchProgress) { calls++ })
+	if err != nil {
+		t.Fatalf("Prefetch = %v, want nil error", err)
+	}
+	// generation, region, location, area and 3 species with their pokemon
+	if summary.Total != 10 || summary.Done != 10 || calls != 10 {
+		t.Fatalf("summary = %+v after %d progress calls, want 10 resources", summary.PrefetchProgress, calls)
+	}
+	if summary.Failed != 1 || summary.Failures[0].URL != api+"pokemon/rattata" {
+		t.Fatalf("failures = %+v, want only pokemon/rattata", summary.Failures)
+	}
+	for url := range kantoResponses {
+		if fake.requests[url] != 1 {
+			t.Errorf("%s requested %d times, want 1", url, fake.requests[url])
+		}
+		if _, ok := cache.Get(url); !ok {
+			t.Errorf("%s not cached after prefetch", url)
+		}
+	}
+}
+
+func TestPrefetchResumesFromCache(t *testing.T) {
+	responses := map[string]string{api + "pokemon/rattata": `{"name":"rattata"}`}
+	for url, body := range kantoResponses {
+		responses[url] = body
+	}
+	fake := &fakeTransport{responses: responses, requests: make(map[string]int)}
+	cl := &Client{HTTP: &http.Client{Transport: fake}}
+	// The default cache_ttl, stale_window and disk_ttl settings
+	clock := &stoppedClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	dir := t.TempDir()
+	newCache := func() *pokecache.Cache {
+		return pokecache.NewCache(30*time.Second, pokecache.WithStale(10*time.Minute), pokecache.WithClock(clock), pokecache.WithDir(dir, 168*time.Hour))
+	}
+	first := newCache()
+	if _, err := cl.Prefetch(context.Background(), "region", "kanto", first, 2, nil); err != nil {
+		t.Fatal(err)
+	}
+	first.Close()
+	requests := func() int {
+		n := 0
+		for _, count := range fake.requests {
+			n += count
+		}
+		return n
+	}
+	fetched := requests()
+	if fetched == 0 {
+		t.Fatal("prefetch made no requests through the client")
+	}
+
+	// Resume minutes later, past the memory interval but well within the disk ttl
+	clock.Advance(15 * time.Minute)
+	second := newCache()
+	defer second.Close()
+	summary, err := cl.Prefetch(context.Background(), "region", "kanto", second, 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Cached != summary.Done || summary.Failed != 0 {
+		t.Errorf("resumed summary = %+v, want every resource cached", summary.PrefetchProgress)
+	}
+	if n := requests() - fetched; n != 0 {
+		t.Errorf("resumed prefetch made %d requests, want none: %v", n, fake.requests)
+	}
+}
+
+func TestPrefetchCancelled(t *testing.T) {
+	useFakeAPI(t, kantoResponses)
+	cache := pokecache.NewCache(time.Hour)
+	defer cache.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	summary, err := Prefetch(ctx, "generation", "1", cache, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !summary.Interrupted || summary.Done != 0 {
+		t.Fatalf("summary = %+v, want an interrupted prefetch with nothing done", summary)
+	}
+}
+
+func TestPrefetchUnknownKind(t *testing.T) {
+	if _, err := Prefetch(context.Background(), "pokemon", "pikachu", nil, 1, nil); err == nil {
+		t.Fatalf("Prefetch of an unknown kind = nil error, want error")
+	}
+}
@@ -0,0 +1,83 @@
+package pokeapi
+
+import (
+	"bytes"
+	"errors"
+	"internal/pokecache"
+	"net/http"
+	"strings"
+)
+
+// HTTP handler mirroring the PokeAPI v2 path layout from a cache
+// Requests for /api/v2/<path> are forwarded to BaseURL + <path>
+// Misses are filled from the API; concurrent requests for one URL share a single API request
+// Expired entries in the cache's stale window are served if the API request fails
+// Links to the API in responses are rewritten to point back at the proxy
+type Proxy struct {
+	Cache *pokecache.Cache
+	// Makes the API requests; the package's default client when nil
+	Client *Client
+}
+
+// Serves GET and HEAD requests under /api/v2/
+// Sets X-Cache to HIT, MISS or STALE to show where the response came from
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, "/api/v2/") {
+		http.NotFound(w, r)
+		return
+	}
+	httpRequest := BaseURL + strings.TrimPrefix(r.URL.Path, "/api/v2/")
+	if r.URL.RawQuery != "" {
+		httpRequest += "?" + r.URL.RawQuery
+	}
+
+	body, cacheStatus, err := p.fetch(httpRequest)
+	var notFound *NotFoundError
+	switch {
+	case errors.As(err, &notFound):
+		http.Error(w, "Not Found", http.StatusNotFound)
+	case err != nil:
+		Logger.Error("proxy upstream request failed", "url", httpRequest, "err", err)
+		http.Error(w, "upstream request failed", http.StatusBadGateway)
+	default:
+		body = bytes.ReplaceAll(body, []byte(BaseURL), []byte(proxyBase(r)+"/api/v2/"))
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("X-Cache", cacheStatus)
+		w.Write(body)
+	}
+}
+
+// Returns the body for httpRequest and whether it was a cache HIT, MISS or STALE
+func (p *Proxy) fetch(httpRequest string) ([]byte, string, error) {
+	if p.Cache.Missing(httpRequest) {
+		return nil, "HIT", &NotFoundError{httpRequest}
+	}
+	val, fresh, found := p.Cache.Lookup(httpRequest)
+	if found && fresh {
+		return val, "HIT", nil
+	}
+	client := p.Client
+	if client == nil {
+		client = defaultClient
+	}
+	body, err := client.fillResource(httpRequest, p.Cache)
+	var notFound *NotFoundError
+	if err != nil && found && !errors.As(err, &notFound) {
+		return val, "STALE", nil
+	}
+	return body, "MISS", err
+}
+
+// Returns the scheme and host clients used to reach the proxy
+func proxyBase(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
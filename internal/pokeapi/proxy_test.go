@@ -0,0 +1,110 @@
+package pokeapi
+
+import (
+	"internal/pokecache"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func proxyGet(p *Proxy, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://localhost:8080"+path, nil))
+	return rec
+}
+
+func TestProxyServesFromCache(t *testing.T) {
+	fake := &fakeTransport{responses: map[string]string{
+		api + "location-area/?limit=20": `{"next":"https://pokeapi.co/api/v2/location-area/?offset=20&limit=20","results":[]}`,
+	}, requests: make(map[string]int)}
+	cache := pokecache.NewCache(time.Minute)
+	defer cache.Close()
+	// Requests go through the proxy's client rather than http.DefaultClient
+	p := &Proxy{Cache: cache, Client: &Client{HTTP: &http.Client{Transport: fake}}}
+
+	first := proxyGet(p, "/api/v2/location-area/?limit=20")
+	if first.Code != http.StatusOK || first.Header().Get("X-Cache") != "MISS" {
+		t.Fatalf("first request = %d %s, want 200 MISS", first.Code, first.Header().Get("X-Cache"))
+	}
+	if !strings.Contains(first.Body.String(), `"next":"http://localhost:8080/api/v2/location-area/?offset=20&limit=20"`) {
+		t.Fatalf("links not rewritten to the proxy: %s", first.Body.String())
+	}
+	second := proxyGet(p, "/api/v2/location-area/?limit=20")
+	if second.Code != http.StatusOK || second.Header().Get("X-Cache") != "HIT" {
+		t.Fatalf("second request = %d %s, want 200 HIT", second.Code, second.Header().Get("X-Cache"))
+	}
+	if fake.requests[api+"location-area/?limit=20"] != 1 {
+		t.Fatalf("upstream requested %d times, want 1", fake.requests[api+"location-area/?limit=20"])
+	}
+}
+
+func TestProxyNotFound(t *testing.T) {
+	fake := useFakeAPI(t, map[string]string{})
+	cache := pokecache.NewCache(time.Minute, pokecache.WithNegativeTTL(time.Minute))
+	defer cache.Close()
+	p := &Proxy{Cache: cache}
+
+	for i := 0; i < 2; i++ {
+		if rec := proxyGet(p, "/api/v2/location-area/mt-moon"); rec.Code != http.StatusNotFound {
+			t.Fatalf("request = %d, want 404", rec.Code)
+		}
+	}
+	if fake.requests[api+"location-area/mt-moon"] != 1 {
+		t.Fatalf("upstream requested %d times, want 1", fake.requests[api+"location-area/mt-moon"])
+	}
+	if rec := proxyGet(p, "/other"); rec.Code != http.StatusNotFound {
+		t.Fatalf("request outside /api/v2/ = %d, want 404", rec.Code)
+	}
+}
+
+func TestProxyCoalescesMisses(t *testing.T) {
+	fake := useFakeAPI(t, map[string]string{api + "pokemon/pikachu": `{"name":"pikachu"}`})
+	cache := pokecache.NewCache(time.Minute)
+	defer cache.Close()
+	p := &Proxy{Cache: cache}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if rec := proxyGet(p, "/api/v2/pokemon/pikachu"); rec.Code != http.StatusOK {
+				t.Errorf("request = %d, want 200", rec.Code)
+			}
+		}()
+	}
+	wg.Wait()
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	// Requests arriving after the first fill completes are cache hits
+	if n := fake.requests[api+"pokemon/pikachu"]; n != 1 {
+		t.Fatalf("upstream requested %d times, want 1", n)
+	}
+}
+
+func TestProxyRejectsOtherMethods(t *testing.T) {
+	p := &Proxy{Cache: pokecache.NewCache(time.Minute)}
+	defer p.Cache.Close()
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v2/pokemon/pikachu", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("POST = %d, want 405", rec.Code)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	SetRateLimit(100, 2)
+	defer SetRateLimit(0, 0)
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		upstreamLimiter.Load().wait()
+	}
+	// Two requests use the burst and the other three wait 10ms each; allow 5ms for timer slack
+	const want = 25 * time.Millisecond
+	if elapsed := time.Since(start); elapsed < want {
+		t.Fatalf("5 requests at 100/s with a burst of 2 took %s, want at least %s", elapsed, want)
+	}
+}
@@ -0,0 +1,56 @@
+package pokeapi
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Token bucket limiting how often requests are sent to the API
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// Limiter applied to every API request; nil means unlimited
+// Swapped atomically, as SetRateLimit may run while requests are in flight
+var upstreamLimiter atomic.Pointer[rateLimiter]
+
+// Limits requests to the API to perSecond on average, allowing bursts of up to burst requests
+// A rate of zero or less removes the limit
+func SetRateLimit(perSecond float64, burst int) {
+	if perSecond <= 0 {
+		upstreamLimiter.Store(nil)
+		return
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	upstreamLimiter.Store(&rateLimiter{
+		rate:   perSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	})
+}
+
+// Blocks until a request may be sent
+func (l *rateLimiter) wait() {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	l.tokens--
+	//A negative balance is the time this request must wait for its token
+	delay := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+}
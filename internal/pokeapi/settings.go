@@ -0,0 +1,49 @@
+package pokeapi
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Root of the public PokeAPI v2 endpoints
+const DefaultBaseURL = "https://pokeapi.co/api/v2/"
+
+// Root of the PokeAPI v2 endpoints requests are made to, ending in a slash
+// Point it at a mirror, such as a pokedexcli proxy, to share one cache
+var BaseURL = DefaultBaseURL
+
+// Number of location areas on each map page
+var PageSize = 20
+
+// Language code, such as "en", used for localized names
+var Language = "en"
+
+// When set, explore lists only the pokemon found in this game version, such as "diamond"
+var GameVersion string
+
+// Source of the rolls made when catching pokemon
+var random = struct {
+	sync.Mutex
+	*rand.Rand
+}{Rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
+// Makes catch rolls repeat for the same seed
+func SetSeed(seed int64) {
+	random.Lock()
+	defer random.Unlock()
+	random.Rand = rand.New(rand.NewSource(seed))
+}
+
+// Returns a random number in [0, 1) from the catch roll source
+func randomFloat() float64 {
+	random.Lock()
+	defer random.Unlock()
+	return random.Float64()
+}
+
+// Returns the URL of the API resource at path under BaseURL
+func apiURL(format string, args ...any) string {
+	return BaseURL + fmt.Sprintf(format, args...)
+}
@@ -0,0 +1,73 @@
+package pokeapi
+
+import (
+	"internal/pokecache"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const canalaveArea = `{"names":[{"language":{"name":"fr"},"name":"Zone de Joliberges"},{"language":{"name":"en"},"name":"Canalave City"}],
+"pokemon_encounters":[
+{"pokemon":{"name":"tentacool"},"version_details":[{"version":{"name":"diamond"}},{"version":{"name":"pearl"}}]},
+{"pokemon":{"name":"staryu"},"version_details":[{"version":{"name":"platinum"}}]}]}`
+
+func TestExploreLanguageAndVersion(t *testing.T) {
+	useFakeAPI(t, map[string]string{api + "location-area/canalave-city-area": canalaveArea})
+	cache := pokecache.NewCache(time.Minute)
+	defer cache.Close()
+	defer func() { Language, GameVersion = "en", "" }()
+
+	cases := []struct {
+		language, version string
+		display           string
+		pokemon           []string
+	}{
+		{"en", "", "Canalave City", []string{"tentacool", "staryu"}},
+		{"fr", "pearl", "Zone de Joliberges", []string{"tentacool"}},
+		{"ja", "platinum", "canalave-city-area", []string{"staryu"}},
+		{"en", "red", "Canalave City", []string{}},
+	}
+	for _, c := range cases {
+		Language, GameVersion = c.language, c.version
+		got, err := Explore("canalave-city-area", cache)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.DisplayName != c.display || !reflect.DeepEqual(got.Pokemon, c.pokemon) {
+			t.Errorf("Explore in %s/%s = %q %q, want %q %q", c.language, c.version, got.DisplayName, got.Pokemon, c.display, c.pokemon)
+		}
+	}
+}
+
+func TestBaseURLAndPageSize(t *testing.T) {
+	mirror := "http://localhost:8080/api/v2/"
+	fake := useFakeAPI(t, map[string]string{
+		mirror + "location-area/?limit=2": `{"next":"` + mirror + `location-area/?offset=2&limit=2","results":[{"name":"a"},{"name":"b"}]}`,
+	})
+	cache := pokecache.NewCache(time.Minute)
+	defer cache.Close()
+	defer func() { BaseURL, PageSize = DefaultBaseURL, 20 }()
+	BaseURL, PageSize = mirror, 2
+
+	areas, err := (&Client{}).GetAreaLocation(1, cache)
+	if err != nil || !reflect.DeepEqual(areas, []string{"a", "b"}) {
+		t.Fatalf("GetAreaLocation = %q, %v", areas, err)
+	}
+	if fake.requests[mirror+"location-area/?limit=2"] != 1 {
+		t.Errorf("mirror not requested: %v", fake.requests)
+	}
+	if err := (&NotFoundError{mirror + "pokemon/missingno"}).Error(); err != "pokemon/missingno was not found" {
+		t.Errorf("NotFoundError = %q, want the path under BaseURL", err)
+	}
+}
+
+func TestSetSeedRepeatsRolls(t *testing.T) {
+	roll := func() []float64 {
+		SetSeed(42)
+		return []float64{randomFloat(), randomFloat(), randomFloat()}
+	}
+	if first, second := roll(), roll(); !reflect.DeepEqual(first, second) {
+		t.Errorf("rolls %v and %v differ for the same seed", first, second)
+	}
+}
@@ -0,0 +1,69 @@
+package pokeapi
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// One resource fetched while a trace was recording
+type TraceEvent struct {
+	URL string `json:"url"`
+	// Where the response came from: memory, disk, stale, negative or network
+	Source string `json:"source"`
+	// HTTP status of a network request; 0 when none was made or it got no response
+	Status  int           `json:"status,omitempty"`
+	Bytes   int           `json:"bytes"`
+	Start   time.Time     `json:"start"`
+	Latency time.Duration `json:"latency_ns"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// Resources fetched between StartTrace and StopTrace
+type trace struct {
+	mu     sync.Mutex
+	events []TraceEvent
+}
+
+// Starts recording every resource the client fetches
+// Returns false without starting a trace if one is already in progress; its StopTrace ends it
+func (cl *Client) StartTrace() bool {
+	return cl.trace.CompareAndSwap(nil, &trace{})
+}
+
+// Stops recording and returns the resources fetched since StartTrace, in the order they finished
+// Returns nil if no trace was in progress
+func (cl *Client) StopTrace() []TraceEvent {
+	t := cl.trace.Swap(nil)
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.events
+}
+
+// Completes event with the outcome of its fetch and records it
+func (t *trace) add(event *TraceEvent, body []byte, err error) {
+	event.Latency = time.Since(event.Start)
+	event.Bytes = len(body)
+	if err != nil {
+		event.Error = err.Error()
+	}
+	if event.Source == "network" {
+		var notFound *NotFoundError
+		var status *StatusError
+		switch {
+		case err == nil:
+			event.Status = http.StatusOK
+		case errors.As(err, &notFound):
+			event.Status = http.StatusNotFound
+		case errors.As(err, &status):
+			event.Status = status.Code
+		}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.events = append(t.events, *event)
+}
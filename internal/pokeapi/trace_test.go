@@ -0,0 +1,56 @@
+package pokeapi
+
+import (
+	"internal/pokecache"
+	"testing"
+	"time"
+)
+
+func TestTraceRecordsFetches(t *testing.T) {
+	useFakeAPI(t, map[string]string{api + "pokemon-species/mew": `{"name":"mew"}`})
+	cache := pokecache.NewCache(time.Minute, pokecache.WithNegativeTTL(time.Minute))
+	defer cache.Close()
+
+	cl := &Client{}
+	cl.fetchResource(api+"pokemon-species/mew", cache)
+	if !cl.StartTrace() {
+		t.Fatalf("StartTrace = false with no trace in progress")
+	}
+	cl.fetchResource(api+"pokemon-species/mew", cache)
+	if cl.StartTrace() {
+		t.Errorf("StartTrace = true while a trace was in progress, want false")
+	}
+	cl.fetchResource(api+"pokemon-species/missingno", cache)
+	cl.fetchResource(api+"pokemon-species/missingno", cache)
+	events := cl.StopTrace()
+
+	want := []struct {
+		url    string
+		source string
+		status int
+		bytes  int
+	}{
+		{api + "pokemon-species/mew", "memory", 0, 14},
+		{api + "pokemon-species/missingno", "network", 404, 0},
+		{api + "pokemon-species/missingno", "negative", 0, 0},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("StopTrace() = %d events, want %d: %+v", len(events), len(want), events)
+	}
+	for i, w := range want {
+		e := events[i]
+		if e.URL != w.url || e.Source != w.source || e.Status != w.status || e.Bytes != w.bytes {
+			t.Errorf("event %d = %+v, want %s from %s with status %d and %d bytes", i, e, w.url, w.source, w.status, w.bytes)
+		}
+		if e.Start.IsZero() || e.Latency < 0 || (w.source != "memory" && e.Error == "") {
+			t.Errorf("event %d = %+v, want a start time, latency and error", i, e)
+		}
+	}
+	if cl.StopTrace() != nil {
+		t.Error("StopTrace() after stopping returned events, want nil")
+	}
+	cl.fetchResource(api+"pokemon-species/mew", cache)
+	if cl.trace.Load() != nil {
+		t.Error("fetch without a trace started one")
+	}
+}
@@ -0,0 +1,64 @@
+package pokecache
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Fills a cache the way a prefetch would and returns the keys used
+func prefetchedCache(b *testing.B, shards int, entries int) (*Cache, []string) {
+	b.Helper()
+	// A short interval keeps the reaper locking shards throughout the benchmark
+	cache := NewCache(time.Millisecond, WithShards(shards), WithStale(time.Hour))
+	b.Cleanup(func() { cache.Close() })
+	keys := make([]string, entries)
+	val := make([]byte, 512)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("https://pokeapi.co/api/v2/pokemon/%d", i)
+		cache.Add(keys[i], val)
+	}
+	return cache, keys
+}
+
+// Many goroutines looking up and filling entries at once, as parallel prefetching does
+func BenchmarkParallelPrefetch(b *testing.B) {
+	for _, shards := range []int{1, 4, defaultShards, 64} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			cache, keys := prefetchedCache(b, shards, 10000)
+			val := make([]byte, 512)
+			var workers atomic.Int64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				// Each worker walks the keys from its own offset
+				i := int(workers.Add(1)) * 7919
+				for pb.Next() {
+					i++
+					key := keys[i%len(keys)]
+					if _, _, found := cache.Lookup(key); !found || i%8 == 0 {
+						cache.Add(key, val)
+					}
+				}
+			})
+		})
+	}
+}
+
+// Readers only, measuring how much the reaper stalls them
+func BenchmarkParallelLookup(b *testing.B) {
+	for _, shards := range []int{1, defaultShards} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			cache, keys := prefetchedCache(b, shards, 10000)
+			var workers atomic.Int64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := int(workers.Add(1)) * 7919
+				for pb.Next() {
+					i++
+					cache.Lookup(keys[i%len(keys)])
+				}
+			})
+		})
+	}
+}
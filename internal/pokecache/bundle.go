@@ -0,0 +1,129 @@
+package pokecache
+
+import (
+    "bufio"
+    "compress/gzip"
+    "encoding/json"
+    "fmt"
+    "io"
+    "sort"
+    "time"
+)
+
+//Format version written to bundles by Export
+const bundleVersion = 1
+
+//Header at the start of a bundle describing its contents
+type BundleInfo struct {
+    Version int `json:"version"`
+    ExportedAt time.Time `json:"exported_at"`
+    Entries int `json:"entries"`
+}
+
+//A single cached response within a bundle
+//Value holds the uncompressed response
+type bundleEntry struct {
+    Key string `json:"key"`
+    CreatedAt time.Time `json:"created_at"`
+    Size int `json:"size"`
+    Value []byte `json:"value"`
+}
+
+//Writes every value that has not been reaped to w as a portable bundle,
+//along with the values in the persistent tier within the disk ttl that are not in memory
+//A bundle is a gzip stream of newline separated JSON: a BundleInfo header then one line per entry
+//Entries are written in key order with their creation time and size
+//Returns the header written
+func (c *Cache) Export(w io.Writer) (BundleInfo, error) {
+    entries := c.snapshot()
+    info := BundleInfo{
+        Version: bundleVersion,
+        ExportedAt: c.clock.Now(),
+        Entries: len(entries),
+    }
+    zw := gzip.NewWriter(w)
+    enc := json.NewEncoder(zw)
+    if err := enc.Encode(info); err != nil {
+        return info, err
+    }
+    for _, be := range entries {
+        if err := enc.Encode(be); err != nil {
+            return info, err
+        }
+    }
+    return info, zw.Close()
+}
+
+//Reads a bundle written by Export from r and adds its entries
+//Imported entries are fresh for a full interval, however long ago they were exported, like values read from disk
+//They keep their creation time, so a persistent tier drops them once they are older than its ttl;
+//entries without one are stamped with the current time
+//Existing values with the same key are replaced
+//Returns the bundle header; on error, entries read before it are kept
+func (c *Cache) Import(r io.Reader) (BundleInfo, error) {
+    var info BundleInfo
+    zr, err := gzip.NewReader(r)
+    if err != nil {
+        return info, fmt.Errorf("reading bundle: %w", err)
+    }
+    defer zr.Close()
+    dec := json.NewDecoder(bufio.NewReader(zr))
+    if err := dec.Decode(&info); err != nil {
+        return info, fmt.Errorf("reading bundle header: %w", err)
+    }
+    if info.Version != bundleVersion {
+        return info, fmt.Errorf("unsupported bundle version %d", info.Version)
+    }
+    for i := 0; i < info.Entries; i++ {
+        var be bundleEntry
+        if err := dec.Decode(&be); err != nil {
+            return info, fmt.Errorf("reading bundle entry %d: %w", i+1, err)
+        }
+        if be.Size != len(be.Value) {
+            return info, fmt.Errorf("bundle entry %q has %d bytes, want %d", be.Key, len(be.Value), be.Size)
+        }
+        c.addAt(be.Key, be.Value, be.CreatedAt)
+    }
+    return info, nil
+}
+
+//Returns a copy of every value that has not been reaped, sorted by key
+//Values in the persistent tier are included unless memory holds the same or a newer one
+func (c *Cache) snapshot() []bundleEntry {
+    now := c.clock.Now()
+    var entries []bundleEntry
+    if c.disk != nil {
+        entries = c.disk.entries(now)
+    }
+    onDisk := make(map[string]int, len(entries))
+    for i, be := range entries {
+        onDisk[be.Key] = i
+    }
+    for i := range c.shards {
+        sh := &c.shards[i]
+        sh.mu.RLock()
+        for key, ce := range sh.cachedValues {
+            if c.reapable(ce, now) {
+                continue
+            }
+            val, ok := c.value(ce)
+            if !ok {
+                continue
+            }
+            be := bundleEntry{key, ce.createdAt, len(val), val}
+            if j, ok := onDisk[key]; ok {
+                if entries[j].CreatedAt.After(ce.createdAt) {
+                    continue
+                }
+                entries[j] = be
+                continue
+            }
+            entries = append(entries, be)
+        }
+        sh.mu.RUnlock()
+    }
+    sort.Slice(entries, func(i, j int) bool {
+        return entries[i].Key < entries[j].Key
+    })
+    return entries
+}
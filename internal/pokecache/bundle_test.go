@@ -0,0 +1,157 @@
+package pokecache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportImportRoundTrip(t *testing.T) {
+	verifyNoLeaks(t)
+	clock := newFakeClock()
+	src := NewCache(time.Minute, WithClock(clock), WithCompression(16))
+	defer src.Close()
+	values := map[string][]byte{
+		"https://pokeapi.co/api/v2/pokemon/pikachu": bytes.Repeat([]byte("pika"), 100),
+		"https://pokeapi.co/api/v2/pokemon/mew":     []byte(`{"name":"mew"}`),
+	}
+	for key, val := range values {
+		src.Add(key, val)
+	}
+
+	var buf bytes.Buffer
+	info, err := src.Export(&buf)
+	if err != nil {
+		t.Fatalf("Export = %v, want nil error", err)
+	}
+	if info.Entries != 2 || !info.ExportedAt.Equal(clock.Now()) {
+		t.Fatalf("Export header = %+v, want 2 entries exported at %v", info, clock.Now())
+	}
+
+	dst := NewCache(time.Minute, WithClock(clock))
+	defer dst.Close()
+	imported, err := dst.Import(&buf)
+	if err != nil {
+		t.Fatalf("Import = %v, want nil error", err)
+	}
+	if imported != info {
+		t.Fatalf("Import header = %+v, want %+v", imported, info)
+	}
+	for key, want := range values {
+		if got, ok := dst.Get(key); !ok || !bytes.Equal(got, want) {
+			t.Errorf("Get(%q) after Import = %q, %v, want the exported value", key, got, ok)
+		}
+	}
+}
+
+func TestImportOfAnOldBundle(t *testing.T) {
+	verifyNoLeaks(t)
+	clock := newFakeClock()
+	exportedAt := clock.Now()
+	src := NewCache(time.Hour, WithClock(clock))
+	defer src.Close()
+	src.Add("key", []byte("val"))
+	var buf bytes.Buffer
+	if _, err := src.Export(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// Imported long after the export, the entry is fresh for a full interval
+	clock.Advance(48 * time.Hour)
+	dir := t.TempDir()
+	dst := NewCache(time.Minute, WithClock(clock), WithDir(dir, 24*time.Hour))
+	defer dst.Close()
+	if _, err := dst.Import(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if val, ok := dst.Get("key"); !ok || string(val) != "val" {
+		t.Fatalf(`Get after Import = %q, %v, want "val"`, val, ok)
+	}
+
+	// It keeps its creation time, so it is exported as it was and its disk copy is past the disk ttl
+	buf.Reset()
+	if _, err := dst.Export(&buf); err != nil {
+		t.Fatal(err)
+	}
+	again := NewCache(0, WithClock(clock))
+	defer again.Close()
+	again.Import(&buf)
+	if entries := again.snapshot(); len(entries) != 1 || !entries[0].CreatedAt.Equal(exportedAt) {
+		t.Fatalf("re-exported entries = %+v, want one created at %v", entries, exportedAt)
+	}
+	dst.Flush()
+	clock.Advance(2 * time.Minute)
+	if _, ok := dst.Get("key"); ok {
+		t.Fatalf("Get past the interval after Import = hit, want miss")
+	}
+	restarted := NewCache(time.Minute, WithClock(clock), WithDir(dir, 24*time.Hour))
+	defer restarted.Close()
+	if _, ok := restarted.Get("key"); ok {
+		t.Fatalf("Get from disk of an entry older than the disk ttl = hit, want miss")
+	}
+}
+
+func TestExportIncludesDiskTier(t *testing.T) {
+	verifyNoLeaks(t)
+	dir := t.TempDir()
+	clock := newFakeClock()
+	writer := NewCache(time.Minute, WithClock(clock), WithDir(dir, time.Hour))
+	writer.Add("disk", []byte("saved"))
+	writer.Add("both", []byte("old"))
+	writer.Close()
+
+	clock.Advance(10 * time.Minute)
+	cache := NewCache(time.Minute, WithClock(clock), WithDir(dir, time.Hour))
+	defer cache.Close()
+	cache.Add("both", []byte("new"))
+	cache.Add("memory", []byte("val"))
+	var buf bytes.Buffer
+	info, err := cache.Export(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Entries != 3 {
+		t.Fatalf("Export header = %+v, want 3 entries", info)
+	}
+	dst := NewCache(time.Minute, WithClock(clock))
+	defer dst.Close()
+	if _, err := dst.Import(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]string{"disk": "saved", "both": "new", "memory": "val"} {
+		if val, ok := dst.Get(key); !ok || string(val) != want {
+			t.Errorf("Get(%q) after Import = %q, %v, want %q", key, val, ok, want)
+		}
+	}
+}
+
+func TestImportRejectsBadBundles(t *testing.T) {
+	verifyNoLeaks(t)
+	cache := NewCache(time.Minute)
+	defer cache.Close()
+
+	if _, err := cache.Import(strings.NewReader("not gzip")); err == nil {
+		t.Errorf("Import of non-gzip data = nil error, want error")
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(`{"version":99,"entries":0}` + "\n"))
+	zw.Close()
+	if _, err := cache.Import(&buf); err == nil || !strings.Contains(err.Error(), "version") {
+		t.Errorf("Import of an unknown version = %v, want version error", err)
+	}
+
+	buf.Reset()
+	zw = gzip.NewWriter(&buf)
+	zw.Write([]byte(`{"version":1,"entries":2}` + "\n" + `{"key":"a","size":1,"value":"YQ=="}` + "\n"))
+	zw.Close()
+	if _, err := cache.Import(&buf); err == nil {
+		t.Errorf("Import of a truncated bundle = nil error, want error")
+	}
+	if _, ok := cache.Get("a"); !ok {
+		t.Errorf("Import dropped the entry read before the error")
+	}
+}
@@ -0,0 +1,35 @@
+package pokecache
+
+import "time"
+
+//Source of the current time and tickers used by the cache
+//Swapped out in tests so expiry does not depend on wall time
+type Clock interface {
+    Now() time.Time
+    NewTicker(d time.Duration) Ticker
+}
+
+//Ticker delivering ticks on Chan until stopped
+type Ticker interface {
+    Chan() <-chan time.Time
+    Stop()
+}
+
+//Clock backed by the time package
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+    return time.Now()
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+    return realTicker{time.NewTicker(d)}
+}
+
+type realTicker struct {
+    *time.Ticker
+}
+
+func (t realTicker) Chan() <-chan time.Time {
+    return t.C
+}
@@ -0,0 +1,120 @@
+package pokecache
+
+import (
+    "bytes"
+    "compress/gzip"
+    "hash/maphash"
+    "io"
+    "time"
+)
+
+//Seed shared by Cache and Typed so their response hashes can be compared
+var hashSeed = maphash.MakeSeed()
+
+//Compresses stored responses of at least threshold bytes with gzip
+//Get and Lookup return the original bytes; responses that do not shrink are stored as is
+func WithCompression(threshold int) Option {
+    return func(c *Cache) {
+        c.compressAt = threshold
+    }
+}
+
+//Counts describing the cache contents and how it has been used
+//Negative counts cover keys recorded by AddMissing and are not included in Hits or Misses
+type Stats struct {
+    Shards int
+    Entries int
+    Compressed int
+    RawBytes int64
+    StoredBytes int64
+    Hits int64
+    StaleHits int64
+    DiskHits int64
+    Misses int64
+    NegativeEntries int
+    NegativeHits int64
+}
+
+//Returns the uncompressed size divided by the stored size
+//Returns 1 for an empty cache
+func (s Stats) CompressionRatio() float64 {
+    if s.StoredBytes == 0 {
+        return 1
+    }
+    return float64(s.RawBytes) / float64(s.StoredBytes)
+}
+
+//Returns the current counts for the cache
+//Expired values not yet reaped are included in the size counts
+func (c *Cache) Stats() Stats {
+    s := Stats{Shards: len(c.shards)}
+    for i := range c.shards {
+        sh := &c.shards[i]
+        s.Hits += sh.hits.Load()
+        s.StaleHits += sh.staleHits.Load()
+        s.DiskHits += sh.diskHits.Load()
+        s.NegativeHits += sh.negativeHits.Load()
+        s.Misses += sh.misses.Load()
+        sh.mu.RLock()
+        s.Entries += len(sh.cachedValues)
+        s.NegativeEntries += len(sh.missing)
+        for _, ce := range sh.cachedValues {
+            if ce.compressed {
+                s.Compressed++
+            }
+            s.RawBytes += int64(ce.size)
+            s.StoredBytes += int64(len(ce.val))
+        }
+        sh.mu.RUnlock()
+    }
+    return s
+}
+
+//Builds the entry stored for val, compressing it if enabled and worthwhile
+func (c *Cache) newEntry(val []byte) cacheEntry {
+    return c.newEntryAt(val, c.clock.Now())
+}
+
+//Builds the entry stored for val like newEntry, created at the time given rather than now
+//The entry is stored now, so its interval starts now whenever it was created
+func (c *Cache) newEntryAt(val []byte, createdAt time.Time) cacheEntry {
+    ce := cacheEntry{
+        createdAt: createdAt,
+        storedAt: c.clock.Now(),
+        val: val,
+        size: len(val),
+        sum: maphash.Bytes(hashSeed, val),
+    }
+    if c.compressAt <= 0 || len(val) < c.compressAt {
+        return ce
+    }
+    var buf bytes.Buffer
+    zw := gzip.NewWriter(&buf)
+    if _, err := zw.Write(val); err != nil {
+        return ce
+    }
+    if err := zw.Close(); err != nil || buf.Len() >= len(val) {
+        return ce
+    }
+    ce.val = buf.Bytes()
+    ce.compressed = true
+    return ce
+}
+
+//Returns the original bytes held by the entry
+//Returns false if compressed data cannot be read back
+func (c *Cache) value(ce cacheEntry) ([]byte, bool) {
+    if !ce.compressed {
+        return ce.val, true
+    }
+    zr, err := gzip.NewReader(bytes.NewReader(ce.val))
+    if err != nil {
+        return nil, false
+    }
+    val := make([]byte, 0, ce.size)
+    buf := bytes.NewBuffer(val)
+    if _, err := io.Copy(buf, zr); err != nil {
+        return nil, false
+    }
+    return buf.Bytes(), true
+}
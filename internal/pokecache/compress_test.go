@@ -0,0 +1,85 @@
+package pokecache
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestCompressionTransparent(t *testing.T) {
+	verifyNoLeaks(t)
+	cache := NewCache(time.Minute, WithCompression(64))
+	defer cache.Close()
+
+	large := bytes.Repeat([]byte(`{"name":"pikachu"},`), 100)
+	small := []byte(`{"name":"pichu"}`)
+	cache.Add("large", large)
+	cache.Add("small", small)
+
+	if val, ok := cache.Get("large"); !ok || !bytes.Equal(val, large) {
+		t.Fatalf("Get of a compressed value did not return the original bytes")
+	}
+	if val, _, ok := cache.Lookup("large"); !ok || !bytes.Equal(val, large) {
+		t.Fatalf("Lookup of a compressed value did not return the original bytes")
+	}
+	if val, ok := cache.Get("small"); !ok || !bytes.Equal(val, small) {
+		t.Fatalf("Get of a value below the threshold = %q, want %q", val, small)
+	}
+
+	stats := cache.Stats()
+	if stats.Entries != 2 || stats.Compressed != 1 {
+		t.Fatalf("Stats() = %+v, want 2 entries with 1 compressed", stats)
+	}
+	if stats.RawBytes != int64(len(large)+len(small)) {
+		t.Fatalf("Stats().RawBytes = %d, want %d", stats.RawBytes, len(large)+len(small))
+	}
+	if stats.CompressionRatio() <= 1 {
+		t.Fatalf("CompressionRatio() = %f, want above 1", stats.CompressionRatio())
+	}
+}
+
+func TestCompressionSkipsIncompressible(t *testing.T) {
+	verifyNoLeaks(t)
+	cache := NewCache(time.Minute, WithCompression(1))
+	defer cache.Close()
+
+	cache.Add("key", []byte("ab"))
+	if stats := cache.Stats(); stats.Compressed != 0 || stats.CompressionRatio() != 1 {
+		t.Fatalf("Stats() = %+v, want the value stored uncompressed", stats)
+	}
+}
+
+func TestStatsCountsHits(t *testing.T) {
+	verifyNoLeaks(t)
+	clock := newFakeClock()
+	cache := NewCache(time.Second, WithClock(clock), WithStale(time.Minute))
+	defer cache.Close()
+
+	cache.Add("key", []byte("val"))
+	cache.Get("key")
+	cache.Get("missing")
+	clock.Advance(2 * time.Second)
+	cache.Lookup("key")
+
+	stats := cache.Stats()
+	if stats.Hits != 1 || stats.StaleHits != 1 || stats.Misses != 1 {
+		t.Fatalf("Stats() = %+v, want 1 hit, 1 stale hit, 1 miss", stats)
+	}
+}
+
+func TestNoExpiryWithoutInterval(t *testing.T) {
+	verifyNoLeaks(t)
+	clock := newFakeClock()
+	cache := NewCache(0, WithClock(clock))
+	defer cache.Close()
+
+	cache.Add("b", []byte("2"))
+	cache.Add("a", []byte("1"))
+	clock.Advance(24 * time.Hour)
+	if _, ok := cache.Get("a"); !ok {
+		t.Fatalf("Get with no interval = miss, want hit")
+	}
+	if keys := cache.Keys(); len(keys) != 2 || keys[0] != "a" || keys[1] != "b" {
+		t.Fatalf("Keys() = %v, want [a b]", keys)
+	}
+}
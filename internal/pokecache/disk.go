@@ -0,0 +1,215 @@
+package pokecache
+
+import (
+    "bytes"
+    "compress/gzip"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "io/fs"
+    "os"
+    "path/filepath"
+    "sync"
+    "time"
+)
+
+//Persistent tier holding responses as files in a directory
+//Values added to the cache are marked dirty and written out by Flush
+//Files are replaced by atomic rename, so several processes can share the directory
+//and readers never see a partly written file
+type diskTier struct {
+    dir string
+    ttl time.Duration
+    mu *sync.Mutex
+    dirty map[string]bool
+}
+
+//Persists values under dir and reads them back on memory misses
+//Values on disk are fresh until they are older than ttl, however short the interval is
+//A value read back into memory keeps its creation time, so writing it out again does not extend its ttl
+//Writes are deferred until Flush or Close
+func WithDir(dir string, ttl time.Duration) Option {
+    return func(c *Cache) {
+        c.disk = &diskTier{
+            dir: dir,
+            ttl: ttl,
+            mu: &sync.Mutex{},
+            dirty: make(map[string]bool),
+        }
+    }
+}
+
+//Writes every value added since the last Flush to the persistent tier
+//A value whose file holds a newer one, written since by another process, is left as it is
+//Does nothing if the cache has no persistent tier
+//Returns the first error met; values that failed stay dirty for the next Flush
+func (c *Cache) Flush() error {
+    if c.disk == nil {
+        return nil
+    }
+    c.disk.mu.Lock()
+    keys := make([]string, 0, len(c.disk.dirty))
+    for key := range c.disk.dirty {
+        keys = append(keys, key)
+    }
+    c.disk.dirty = make(map[string]bool)
+    c.disk.mu.Unlock()
+
+    var firstErr error
+    for _, key := range keys {
+        ce, _, found := c.entry(c.shardFor(key), key)
+        if !found {
+            continue
+        }
+        val, ok := c.value(ce)
+        if !ok {
+            continue
+        }
+        err := c.disk.writeIfNewer(bundleEntry{key, ce.createdAt, len(val), val})
+        if err != nil {
+            c.log().Warn("cache write failed", "key", key, "err", err)
+            c.disk.markDirty(key)
+            if firstErr == nil {
+                firstErr = err
+            }
+        }
+    }
+    return firstErr
+}
+
+//Records that key must be written by the next Flush
+func (d *diskTier) markDirty(key string) {
+    d.mu.Lock()
+    defer d.mu.Unlock()
+
+    d.dirty[key] = true
+}
+
+//Writes the entry to its file unless the file holds one created later
+//Holds the key's lock so another process cannot write between the check and the write
+func (d *diskTier) writeIfNewer(be bundleEntry) error {
+    unlock, err := d.lock(be.Key)
+    if err != nil {
+        return err
+    }
+    defer unlock()
+    if existing, ok := d.readFile(d.path(be.Key)); ok && existing.Key == be.Key && existing.CreatedAt.After(be.CreatedAt) {
+        return nil
+    }
+    return d.write(be)
+}
+
+//Returns the file holding key
+func (d *diskTier) path(key string) string {
+    sum := sha256.Sum256([]byte(key))
+    name := hex.EncodeToString(sum[:])
+    return filepath.Join(d.dir, name[:2], name+".gz")
+}
+
+//Writes the entry to its file as gzip compressed JSON
+func (d *diskTier) write(be bundleEntry) error {
+    path := d.path(be.Key)
+    if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+        return err
+    }
+    var buf bytes.Buffer
+    zw := gzip.NewWriter(&buf)
+    if err := json.NewEncoder(zw).Encode(be); err != nil {
+        return err
+    }
+    if err := zw.Close(); err != nil {
+        return err
+    }
+    tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+    if err != nil {
+        return err
+    }
+    defer os.Remove(tmp.Name())
+    if _, err := tmp.Write(buf.Bytes()); err != nil {
+        tmp.Close()
+        return err
+    }
+    if err := tmp.Chmod(0644); err != nil {
+        tmp.Close()
+        return err
+    }
+    if err := tmp.Close(); err != nil {
+        return err
+    }
+    return os.Rename(tmp.Name(), path)
+}
+
+//Takes an exclusive lock shared with every process using the directory for key
+//Keys are spread over 256 lock files, so unrelated keys rarely wait on each other
+//Blocks until the lock is held; the returned function releases it
+func (d *diskTier) lock(key string) (func(), error) {
+    sum := sha256.Sum256([]byte(key))
+    dir := filepath.Join(d.dir, "locks")
+    if err := os.MkdirAll(dir, 0755); err != nil {
+        return nil, err
+    }
+    f, err := os.OpenFile(filepath.Join(dir, hex.EncodeToString(sum[:1])+".lock"), os.O_RDWR|os.O_CREATE, 0644)
+    if err != nil {
+        return nil, err
+    }
+    if err := lockFile(f); err != nil {
+        f.Close()
+        return nil, err
+    }
+    return func() {
+        unlockFile(f)
+        f.Close()
+    }, nil
+}
+
+//Reads the entry for key from its file
+//Returns false if there is no file, it cannot be read, or it is older than the ttl at the time now
+func (d *diskTier) read(key string, now time.Time) (bundleEntry, bool) {
+    be, ok := d.readFile(d.path(key))
+    if !ok || be.Key != key || d.expired(be, now) {
+        return be, false
+    }
+    return be, true
+}
+
+//Reads the entry held in the file at path
+//Returns false if it cannot be read or does not hold a whole entry
+func (d *diskTier) readFile(path string) (bundleEntry, bool) {
+    var be bundleEntry
+    f, err := os.Open(path)
+    if err != nil {
+        return be, false
+    }
+    defer f.Close()
+    zr, err := gzip.NewReader(f)
+    if err != nil {
+        return be, false
+    }
+    defer zr.Close()
+    if err := json.NewDecoder(zr).Decode(&be); err != nil || be.Size != len(be.Value) {
+        return be, false
+    }
+    return be, true
+}
+
+//Reports whether the entry is older than the ttl at the time now
+func (d *diskTier) expired(be bundleEntry, now time.Time) bool {
+    return d.ttl > 0 && now.Sub(be.CreatedAt) > d.ttl
+}
+
+//Returns every entry in the directory within the ttl at the time now
+//Files that cannot be read, or whose entry belongs in another file, are skipped
+func (d *diskTier) entries(now time.Time) []bundleEntry {
+    var entries []bundleEntry
+    filepath.WalkDir(d.dir, func(path string, de fs.DirEntry, err error) error {
+        if err != nil || de.IsDir() || filepath.Ext(path) != ".gz" {
+            return nil
+        }
+        be, ok := d.readFile(path)
+        if ok && path == d.path(be.Key) && !d.expired(be, now) {
+            entries = append(entries, be)
+        }
+        return nil
+    })
+    return entries
+}
@@ -0,0 +1,198 @@
+package pokecache
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestDiskTierSurvivesRestart(t *testing.T) {
+	verifyNoLeaks(t)
+	dir := t.TempDir()
+	first := NewCache(time.Minute, WithDir(dir, time.Hour), WithCompression(8))
+	first.Add("key", bytes.Repeat([]byte("val"), 10))
+	if err := first.Close(); err != nil {
+		t.Fatalf("Close() = %v, want nil", err)
+	}
+
+	second := NewCache(time.Minute, WithDir(dir, time.Hour))
+	defer second.Close()
+	val, ok := second.Get("key")
+	if !ok || !bytes.Equal(val, bytes.Repeat([]byte("val"), 10)) {
+		t.Fatalf("Get from a new cache on the same dir = %q, %v, want the flushed value", val, ok)
+	}
+	if stats := second.Stats(); stats.DiskHits != 1 || stats.Entries != 1 {
+		t.Fatalf("Stats() = %+v, want 1 disk hit promoted to memory", stats)
+	}
+	if _, ok := second.Get("key"); !ok || second.Stats().Hits != 1 {
+		t.Fatalf("second Get was not served from memory")
+	}
+}
+
+func TestDiskTierNotWrittenBeforeFlush(t *testing.T) {
+	verifyNoLeaks(t)
+	dir := t.TempDir()
+	first := NewCache(time.Minute, WithDir(dir, time.Hour))
+	defer first.Close()
+	first.Add("key", []byte("val"))
+
+	second := NewCache(time.Minute, WithDir(dir, time.Hour))
+	defer second.Close()
+	if _, ok := second.Get("key"); ok {
+		t.Fatalf("Get before Flush = hit, want miss")
+	}
+	if err := first.Flush(); err != nil {
+		t.Fatalf("Flush() = %v, want nil", err)
+	}
+	if _, ok := second.Get("key"); !ok {
+		t.Fatalf("Get after Flush = miss, want hit")
+	}
+}
+
+func TestDiskTierTTL(t *testing.T) {
+	verifyNoLeaks(t)
+	dir := t.TempDir()
+	clock := newFakeClock()
+	cache := NewCache(time.Minute, WithClock(clock), WithDir(dir, time.Hour))
+	defer cache.Close()
+	cache.Add("key", []byte("val"))
+	cache.Flush()
+
+	// Within the memory interval a new process reads the disk copy as fresh
+	clock.Advance(30 * time.Second)
+	restarted := NewCache(time.Minute, WithClock(clock), WithDir(dir, time.Hour))
+	defer restarted.Close()
+	if _, ok := restarted.Get("key"); !ok {
+		t.Fatalf("Get within the interval and disk ttl = miss, want hit")
+	}
+
+	later := NewCache(time.Minute, WithClock(clock), WithDir(dir, time.Hour))
+	defer later.Close()
+	clock.Advance(2 * time.Hour)
+	if _, ok := later.Get("key"); ok {
+		t.Fatalf("Get past the disk ttl = hit, want miss")
+	}
+}
+
+func TestFlushKeepsNewerDiskCopy(t *testing.T) {
+	verifyNoLeaks(t)
+	dir := t.TempDir()
+	clock := newFakeClock()
+	slow := NewCache(time.Hour, WithClock(clock), WithDir(dir, time.Hour))
+	defer slow.Close()
+	slow.Add("key", []byte("old"))
+
+	clock.Advance(time.Minute)
+	other := NewCache(time.Hour, WithClock(clock), WithDir(dir, time.Hour))
+	other.Add("key", []byte("new"))
+	other.Close()
+
+	if err := slow.Flush(); err != nil {
+		t.Fatalf("Flush = %v", err)
+	}
+	restarted := NewCache(time.Hour, WithClock(clock), WithDir(dir, time.Hour))
+	defer restarted.Close()
+	if val, ok := restarted.Get("key"); !ok || string(val) != "new" {
+		t.Fatalf(`Get after an older value was flushed = %q, %v, want "new"`, val, ok)
+	}
+}
+
+func TestReaperFlushesBeforeReaping(t *testing.T) {
+	verifyNoLeaks(t)
+	dir := t.TempDir()
+	clock := newFakeClock()
+	cache := NewCache(10*time.Second, WithClock(clock), WithShards(1), WithDir(dir, time.Hour))
+	defer cache.Close()
+	cache.Add("key", []byte("val"))
+	clock.Advance(10 * time.Second)
+	clock.Advance(10 * time.Second)
+	waitForLen(t, cache, 0)
+
+	other := NewCache(time.Minute, WithClock(clock), WithDir(dir, time.Hour))
+	defer other.Close()
+	if _, ok := other.Get("key"); !ok {
+		t.Fatalf("reaped value was never written to disk")
+	}
+}
+
+func TestLookupTier(t *testing.T) {
+	verifyNoLeaks(t)
+	dir := t.TempDir()
+	clock := newFakeClock()
+	cache := NewCache(10*time.Second, WithClock(clock), WithStale(time.Minute), WithDir(dir, time.Hour))
+	defer cache.Close()
+	cache.Add("stale", []byte("old"))
+	cache.Flush()
+	// Without its file the expired value can only be served stale from memory
+	os.RemoveAll(dir)
+	clock.Advance(30 * time.Second)
+	cache.Add("memory", []byte("val"))
+
+	other := NewCache(10*time.Second, WithClock(clock), WithDir(dir, time.Hour))
+	other.Add("disk", []byte("saved"))
+	other.Close()
+
+	cases := []struct {
+		key  string
+		val  string
+		tier Tier
+	}{
+		{"memory", "val", TierMemory},
+		{"disk", "saved", TierDisk},
+		{"disk", "saved", TierMemory},
+		{"stale", "old", TierStale},
+		{"none", "", TierMiss},
+	}
+	for _, c := range cases {
+		if val, tier := cache.LookupTier(c.key); string(val) != c.val || tier != c.tier {
+			t.Errorf("LookupTier(%s) = %q, %s, want %q, %s", c.key, val, tier, c.val, c.tier)
+		}
+	}
+}
+
+func TestDiskTierFreshForDiskTTL(t *testing.T) {
+	verifyNoLeaks(t)
+	dir := t.TempDir()
+	clock := newFakeClock()
+	cache := NewCache(30*time.Second, WithClock(clock), WithStale(10*time.Minute), WithDir(dir, 24*time.Hour))
+	defer cache.Close()
+	cache.Add("key", []byte("val"))
+	cache.Flush()
+
+	// Past the memory interval and stale window the disk copy is still within the disk ttl
+	clock.Advance(time.Hour)
+	if val, tier := cache.LookupTier("key"); string(val) != "val" || tier != TierDisk {
+		t.Fatalf(`LookupTier past the stale window with a disk copy = %q, %s, want "val", disk`, val, tier)
+	}
+	if val, tier := cache.LookupTier("key"); string(val) != "val" || tier != TierMemory {
+		t.Fatalf(`LookupTier after reading the disk copy = %q, %s, want "val", memory`, val, tier)
+	}
+
+	// The same holds in a new process reading only the disk copy
+	restarted := NewCache(30*time.Second, WithClock(clock), WithStale(10*time.Minute), WithDir(dir, 24*time.Hour))
+	defer restarted.Close()
+	if _, ok := restarted.Get("key"); !ok {
+		t.Fatalf("Get from disk within the disk ttl = miss, want hit")
+	}
+
+	// Once the memory copy expires the disk copy is read again, still with its first creation time
+	clock.Advance(time.Minute)
+	if _, tier := cache.LookupTier("key"); tier != TierDisk {
+		t.Fatalf("LookupTier after the memory copy expired = %s, want disk", tier)
+	}
+	cache.disk.markDirty("key")
+	cache.Flush()
+	clock.Advance(23 * time.Hour)
+	if val, tier := cache.LookupTier("key"); tier != TierMiss {
+		t.Fatalf("LookupTier past the disk ttl = %q, %s, want miss", val, tier)
+	}
+
+	// A newer copy written by another process replaces the expired memory copy
+	other := NewCache(30*time.Second, WithClock(clock), WithDir(dir, 24*time.Hour))
+	other.Add("key", []byte("new"))
+	other.Close()
+	if val, tier := restarted.LookupTier("key"); string(val) != "new" || tier != TierDisk {
+		t.Fatalf(`LookupTier after another process refreshed it = %q, %s, want "new", disk`, val, tier)
+	}
+}
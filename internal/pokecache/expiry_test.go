@@ -0,0 +1,263 @@
+package pokecache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Clock whose time only moves when Advance is called
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+}
+
+type fakeTicker struct {
+	c        chan time.Time
+	period   time.Duration
+	next     time.Time
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) NewTicker(d time.Duration) Ticker {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTicker{c: make(chan time.Time), period: d, next: f.now.Add(d), stop: make(chan struct{})}
+	f.tickers = append(f.tickers, t)
+	return t
+}
+
+// Moves the clock forward, firing any tickers that came due
+// Unlike time.Ticker, each tick blocks until received or the ticker is stopped
+func (f *fakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	f.now = f.now.Add(d)
+	now := f.now
+	tickers := append([]*fakeTicker(nil), f.tickers...)
+	f.mu.Unlock()
+	for _, t := range tickers {
+		for !t.next.After(now) {
+			select {
+			case t.c <- t.next:
+			case <-t.stop:
+				return
+			}
+			t.next = t.next.Add(t.period)
+		}
+	}
+}
+
+func (t *fakeTicker) Chan() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTicker) Stop() {
+	t.stopOnce.Do(func() { close(t.stop) })
+}
+
+// Waits for the reaper to bring the cache down to want entries
+func waitForLen(t *testing.T, c *Cache, want int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for c.Len() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("Len() = %d, want %d", c.Len(), want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestGetExpiresAfterInterval(t *testing.T) {
+	verifyNoLeaks(t)
+	clock := newFakeClock()
+	cache := NewCache(10*time.Second, WithClock(clock))
+	defer cache.Close()
+
+	cache.Add("key", []byte("val"))
+	clock.Advance(9 * time.Second)
+	clock.Advance(time.Second)
+	if _, ok := cache.Get("key"); !ok {
+		t.Fatalf("Get at exactly the interval = miss, want hit")
+	}
+	clock.Advance(time.Nanosecond)
+	if val, ok := cache.Get("key"); ok {
+		t.Fatalf("Get after the interval = %q, want miss", val)
+	}
+}
+
+func TestAddRefreshesTimestamp(t *testing.T) {
+	verifyNoLeaks(t)
+	clock := newFakeClock()
+	cache := NewCache(10*time.Second, WithClock(clock))
+	defer cache.Close()
+
+	cache.Add("key", []byte("old"))
+	clock.Advance(8 * time.Second)
+	cache.Add("key", []byte("new"))
+	clock.Advance(8 * time.Second)
+	val, ok := cache.Get("key")
+	if !ok || string(val) != "new" {
+		t.Fatalf(`Get = %q, %v, want "new", true`, val, ok)
+	}
+}
+
+func TestReaperRemovesExpired(t *testing.T) {
+	verifyNoLeaks(t)
+	clock := newFakeClock()
+	cache := NewCache(10*time.Second, WithClock(clock), WithShards(1))
+	defer cache.Close()
+
+	cache.Add("old", []byte("1"))
+	clock.Advance(5 * time.Second)
+	cache.Add("young", []byte("2"))
+
+	clock.Advance(5 * time.Second)
+	// Both entries are within the interval at the first tick
+	if cache.Len() != 2 {
+		t.Fatalf("Len() after first tick = %d, want 2", cache.Len())
+	}
+
+	clock.Advance(10 * time.Second)
+	waitForLen(t, cache, 0)
+}
+
+func TestReaperKeepsFreshEntries(t *testing.T) {
+	verifyNoLeaks(t)
+	clock := newFakeClock()
+	cache := NewCache(10*time.Second, WithClock(clock), WithShards(1))
+	defer cache.Close()
+
+	cache.Add("old", []byte("1"))
+	clock.Advance(15 * time.Second)
+	cache.Add("young", []byte("2"))
+	clock.Advance(5 * time.Second)
+
+	waitForLen(t, cache, 1)
+	if _, ok := cache.Get("young"); !ok {
+		t.Fatalf(`Get("young") = miss, want hit`)
+	}
+	if _, ok := cache.Get("old"); ok {
+		t.Fatalf(`Get("old") = hit, want miss`)
+	}
+}
+
+func TestReapUsesTickTime(t *testing.T) {
+	clock := newFakeClock()
+	cache := &Cache{
+		shards:   newShards(1),
+		interval: 10 * time.Second,
+		clock:    clock,
+	}
+	values := cache.shards[0].cachedValues
+	values["a"] = cacheEntry{createdAt: clock.Now(), storedAt: clock.Now(), val: []byte("a")}
+	values["b"] = cacheEntry{createdAt: clock.Now(), storedAt: clock.Now().Add(5 * time.Second), val: []byte("b")}
+	cache.reap(clock.Now().Add(12 * time.Second))
+	if _, ok := values["a"]; ok {
+		t.Errorf("reap kept entry older than the interval")
+	}
+	if _, ok := values["b"]; !ok {
+		t.Errorf("reap removed entry within the interval")
+	}
+}
+
+func TestStoppedTickerDoesNotReap(t *testing.T) {
+	verifyNoLeaks(t)
+	clock := newFakeClock()
+	cache := NewCache(10*time.Second, WithClock(clock))
+	cache.Add("key", []byte("val"))
+	cache.Close()
+
+	clock.Advance(time.Minute)
+	if cache.Len() != 1 {
+		t.Fatalf("Len() after Close = %d, want 1", cache.Len())
+	}
+}
+
+func TestLookupStaleWindow(t *testing.T) {
+	verifyNoLeaks(t)
+	clock := newFakeClock()
+	cache := NewCache(10*time.Second, WithClock(clock), WithStale(time.Minute))
+	defer cache.Close()
+
+	cache.Add("key", []byte("val"))
+	if _, fresh, ok := cache.Lookup("key"); !ok || !fresh {
+		t.Fatalf("Lookup before the interval = fresh %v, found %v, want true, true", fresh, ok)
+	}
+
+	clock.Advance(30 * time.Second)
+	if _, ok := cache.Get("key"); ok {
+		t.Fatalf("Get past the interval = hit, want miss")
+	}
+	val, fresh, ok := cache.Lookup("key")
+	if !ok || fresh || string(val) != "val" {
+		t.Fatalf(`Lookup in the stale window = %q, %v, %v, want "val", false, true`, val, fresh, ok)
+	}
+	if cache.Len() != 1 {
+		t.Fatalf("reaper removed a stale entry inside the window")
+	}
+
+	clock.Advance(time.Minute)
+	if _, _, ok := cache.Lookup("key"); ok {
+		t.Fatalf("Lookup past the stale window = hit, want miss")
+	}
+	waitForLen(t, cache, 0)
+}
+
+func TestLookupWithoutStaleWindow(t *testing.T) {
+	verifyNoLeaks(t)
+	clock := newFakeClock()
+	cache := NewCache(10*time.Second, WithClock(clock))
+	defer cache.Close()
+
+	cache.Add("key", []byte("val"))
+	clock.Advance(11 * time.Second)
+	if _, _, ok := cache.Lookup("key"); ok {
+		t.Fatalf("Lookup past the interval with no stale window = hit, want miss")
+	}
+}
+
+func TestReaperVisitsEveryShardPerInterval(t *testing.T) {
+	verifyNoLeaks(t)
+	clock := newFakeClock()
+	cache := NewCache(10*time.Second, WithClock(clock), WithShards(4))
+	defer cache.Close()
+
+	for i := 0; i < 64; i++ {
+		cache.Add(fmt.Sprintf("key-%d", i), []byte("val"))
+	}
+	clock.Advance(11 * time.Second)
+	if cache.Len() != 64 {
+		t.Fatalf("Len() before expiry = %d, want 64", cache.Len())
+	}
+	// One tick per shard in the next interval sweeps every expired value
+	clock.Advance(10 * time.Second)
+	waitForLen(t, cache, 0)
+}
+
+func TestReapShardLeavesOtherShards(t *testing.T) {
+	clock := newFakeClock()
+	cache := &Cache{
+		shards:   newShards(2),
+		interval: time.Second,
+		clock:    clock,
+	}
+	cache.shards[0].cachedValues["a"] = cacheEntry{createdAt: clock.Now()}
+	cache.shards[1].cachedValues["b"] = cacheEntry{createdAt: clock.Now()}
+	cache.reapShard(&cache.shards[0], clock.Now().Add(time.Minute))
+	if len(cache.shards[0].cachedValues) != 0 || len(cache.shards[1].cachedValues) != 1 {
+		t.Fatalf("reapShard touched a different shard")
+	}
+}
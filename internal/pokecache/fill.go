@@ -0,0 +1,112 @@
+package pokecache
+
+import "sync"
+
+//A fetch in progress for Fill, waited on by every caller for the same key
+type fillCall struct {
+    wg sync.WaitGroup
+    val []byte
+    err error
+}
+
+//Returns the fresh response for key, calling fetch to produce it if there is none
+//Concurrent calls for the same key share a single fetch
+//With a persistent tier the fetch is also shared with other processes using the same directory:
+//a process waiting on another's fetch reads its result from disk instead of fetching again
+//Fetched responses are added to memory and written to the persistent tier immediately
+func (c *Cache) Fill(key string, fetch func() ([]byte, error)) ([]byte, error) {
+    if ce, tier := c.lookup(key, false); tier.fresh() {
+        if val, ok := c.value(ce); ok {
+            return val, nil
+        }
+    }
+
+    c.fillMu.Lock()
+    if call, busy := c.fills[key]; busy {
+        c.fillMu.Unlock()
+        call.wg.Wait()
+        return call.val, call.err
+    }
+    call := c.startFill(key)
+    c.fillMu.Unlock()
+
+    c.runFill(key, call, fetch)
+    return call.val, call.err
+}
+
+//Calls fetch in the background to replace the response for key, such as one served stale
+//Does nothing if a fetch for key is already in progress, so repeated refreshes share one fetch
+//Close waits for refreshes in progress; once it has been called no more are started
+//Returns false if no refresh was started
+func (c *Cache) Refresh(key string, fetch func() ([]byte, error)) bool {
+    c.fillMu.Lock()
+    defer c.fillMu.Unlock()
+
+    if _, busy := c.fills[key]; busy || c.closing.Load() {
+        return false
+    }
+    call := c.startFill(key)
+    c.refreshes.Add(1)
+    go func() {
+        defer c.refreshes.Done()
+        c.runFill(key, call, fetch)
+    }()
+    return true
+}
+
+//Registers a fetch in progress for key for other callers to wait on
+//Must be called with fillMu held
+func (c *Cache) startFill(key string) *fillCall {
+    call := &fillCall{}
+    call.wg.Add(1)
+    c.fills[key] = call
+    return call
+}
+
+//Runs the fetch registered by startFill, then releases the callers waiting on it
+func (c *Cache) runFill(key string, call *fillCall, fetch func() ([]byte, error)) {
+    call.val, call.err = c.fill(key, fetch)
+    call.wg.Done()
+
+    c.fillMu.Lock()
+    delete(c.fills, key)
+    c.fillMu.Unlock()
+}
+
+//Fetches and stores the response for key while holding the persistent tier's lock for it
+func (c *Cache) fill(key string, fetch func() ([]byte, error)) ([]byte, error) {
+    if c.disk == nil {
+        val, err := fetch()
+        if err != nil {
+            return nil, err
+        }
+        c.Add(key, val)
+        return val, nil
+    }
+
+    unlock, err := c.disk.lock(key)
+    if err != nil {
+        return nil, err
+    }
+    defer unlock()
+    sh := c.shardFor(key)
+    //Another process may have fetched it while this one waited for the lock
+    if ce, ok := c.readDisk(key); ok {
+        if val, ok := c.value(ce); ok {
+            c.store(sh, key, ce)
+            sh.diskHits.Add(1)
+            return val, nil
+        }
+    }
+    val, err := fetch()
+    if err != nil {
+        return nil, err
+    }
+    ce := c.newEntry(val)
+    c.store(sh, key, ce)
+    if err := c.disk.write(bundleEntry{key, ce.createdAt, len(val), val}); err != nil {
+        c.log().Warn("cache write failed", "key", key, "err", err)
+        c.disk.markDirty(key)
+    }
+    return val, nil
+}
@@ -0,0 +1,223 @@
+package pokecache
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFillCoalescesConcurrentCalls(t *testing.T) {
+	verifyNoLeaks(t)
+	cache := NewCache(time.Minute)
+	defer cache.Close()
+
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	fetch := func() ([]byte, error) {
+		fetches.Add(1)
+		<-release
+		return []byte("val"), nil
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if val, err := cache.Fill("key", fetch); err != nil || string(val) != "val" {
+				t.Errorf(`Fill = %q, %v, want "val", nil`, val, err)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if fetches.Load() != 1 {
+		t.Fatalf("fetch called %d times, want 1", fetches.Load())
+	}
+}
+
+func TestFillReturnsCachedValue(t *testing.T) {
+	verifyNoLeaks(t)
+	cache := NewCache(time.Minute)
+	defer cache.Close()
+	cache.Add("key", []byte("cached"))
+	val, err := cache.Fill("key", func() ([]byte, error) {
+		t.Fatalf("fetch called for a cached key")
+		return nil, nil
+	})
+	if err != nil || string(val) != "cached" {
+		t.Fatalf(`Fill = %q, %v, want "cached", nil`, val, err)
+	}
+}
+
+func TestFillErrorNotCached(t *testing.T) {
+	verifyNoLeaks(t)
+	cache := NewCache(time.Minute, WithDir(t.TempDir(), time.Hour))
+	defer cache.Close()
+	failure := errors.New("upstream down")
+	if _, err := cache.Fill("key", func() ([]byte, error) { return nil, failure }); err != failure {
+		t.Fatalf("Fill = %v, want the fetch error", err)
+	}
+	if _, ok := cache.Get("key"); ok {
+		t.Fatalf("Get after a failed Fill = hit, want miss")
+	}
+}
+
+func TestFillSharedDirectory(t *testing.T) {
+	verifyNoLeaks(t)
+	dir := t.TempDir()
+	first := NewCache(time.Minute, WithDir(dir, time.Hour))
+	defer first.Close()
+	second := NewCache(time.Minute, WithDir(dir, time.Hour))
+	defer second.Close()
+
+	var fetches atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go first.Fill("key", func() ([]byte, error) {
+		fetches.Add(1)
+		close(started)
+		<-release
+		return []byte("val"), nil
+	})
+	<-started
+	done := make(chan []byte)
+	go func() {
+		val, _ := second.Fill("key", func() ([]byte, error) {
+			fetches.Add(1)
+			return []byte("duplicate"), nil
+		})
+		done <- val
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	if val := <-done; string(val) != "val" {
+		t.Fatalf(`Fill from the waiting cache = %q, want "val" from the first fetch`, val)
+	}
+	if fetches.Load() != 1 {
+		t.Fatalf("fetch called %d times, want 1", fetches.Load())
+	}
+}
+
+// Runs in a child process started by TestFillAcrossProcesses
+func TestFillHelperProcess(t *testing.T) {
+	dir := os.Getenv("POKECACHE_FILL_DIR")
+	if dir == "" {
+		t.Skip("only run as a child of TestFillAcrossProcesses")
+	}
+	cache := NewCache(time.Minute, WithDir(dir, time.Hour))
+	defer cache.Close()
+	_, err := cache.Fill("key", func() ([]byte, error) {
+		f, err := os.OpenFile(filepath.Join(dir, "fetches"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		f.WriteString("fetch\n")
+		time.Sleep(50 * time.Millisecond)
+		return []byte("val"), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFillAcrossProcesses(t *testing.T) {
+	dir := t.TempDir()
+	var cmds []*exec.Cmd
+	for i := 0; i < 4; i++ {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestFillHelperProcess$")
+		cmd.Env = append(os.Environ(), "POKECACHE_FILL_DIR="+dir)
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+		cmds = append(cmds, cmd)
+	}
+	for _, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			t.Fatalf("helper process failed: %v", err)
+		}
+	}
+	fetches, err := os.ReadFile(filepath.Join(dir, "fetches"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(fetches), "fetch"); n != 1 {
+		t.Fatalf("%d processes fetched the key, want 1", n)
+	}
+}
+
+func TestFlushLeavesNoTempFiles(t *testing.T) {
+	verifyNoLeaks(t)
+	dir := t.TempDir()
+	cache := NewCache(time.Minute, WithDir(dir, time.Hour))
+	for _, key := range []string{"a", "b", "c"} {
+		cache.Add(key, []byte(key))
+	}
+	if err := cache.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var files int
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if strings.HasSuffix(path, ".tmp") {
+			t.Errorf("temporary file left behind: %s", path)
+		}
+		if strings.HasSuffix(path, ".gz") {
+			files++
+		}
+		return nil
+	})
+	if files != 3 {
+		t.Fatalf("found %d cache files, want 3", files)
+	}
+}
+
+func TestRefreshRunsOnceAndCloseWaits(t *testing.T) {
+	verifyNoLeaks(t)
+	cache := NewCache(time.Minute)
+
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	fetch := func() ([]byte, error) {
+		fetches.Add(1)
+		<-release
+		return []byte("new"), nil
+	}
+	if !cache.Refresh("key", fetch) {
+		t.Fatalf("Refresh = false, want a refresh started")
+	}
+	for i := 0; i < 5; i++ {
+		if cache.Refresh("key", fetch) {
+			t.Fatalf("Refresh while one is in progress = true, want false")
+		}
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		cache.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+		t.Fatalf("Close returned while a refresh was in progress")
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(release)
+	<-closed
+
+	if fetches.Load() != 1 {
+		t.Fatalf("fetch called %d times, want 1", fetches.Load())
+	}
+	if val, ok := cache.Get("key"); !ok || string(val) != "new" {
+		t.Fatalf(`Get after refresh = %q, %v, want "new", true`, val, ok)
+	}
+	if cache.Refresh("key", fetch) {
+		t.Fatalf("Refresh after Close = true, want false")
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package pokecache
+
+import "os"
+
+//File locking is not supported on this platform
+//Atomic renames still keep files intact, but processes may download the same response
+func lockFile(f *os.File) error {
+    return nil
+}
+
+func unlockFile(f *os.File) error {
+    return nil
+}
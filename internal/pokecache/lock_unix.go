@@ -0,0 +1,23 @@
+//go:build unix
+
+package pokecache
+
+import (
+    "os"
+    "syscall"
+)
+
+//Blocks until an exclusive advisory lock on f is held
+func lockFile(f *os.File) error {
+    for {
+        err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+        if err != syscall.EINTR {
+            return err
+        }
+    }
+}
+
+//Releases the lock taken by lockFile
+func unlockFile(f *os.File) error {
+    return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,26 @@
+package pokecache
+
+import (
+    "io"
+    "log/slog"
+)
+
+//Logger used unless WithLogger is given, discarding every record
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+//Logs cache activity to logger
+//Reads from the persistent tier and reaped values are logged at debug level,
+//values the persistent tier fails to store at warn level
+func WithLogger(logger *slog.Logger) Option {
+    return func(c *Cache) {
+        c.logger = logger
+    }
+}
+
+//Returns the logger given by WithLogger, or one discarding every record
+func (c *Cache) log() *slog.Logger {
+    if c.logger == nil {
+        return discardLogger
+    }
+    return c.logger
+}
@@ -0,0 +1,48 @@
+package pokecache
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoggerRecordsDiskUse(t *testing.T) {
+	verifyNoLeaks(t)
+	dir := t.TempDir()
+	first := NewCache(time.Minute, WithDir(dir, time.Hour))
+	first.Add("key", []byte("val"))
+	first.Close()
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	second := NewCache(time.Minute, WithDir(dir, time.Hour), WithLogger(logger))
+	defer second.Close()
+	second.Get("key")
+	if !strings.Contains(logs.String(), `msg="cache read from disk" key=key`) {
+		t.Errorf("logs after a disk hit = %q, want a disk read record", logs.String())
+	}
+}
+
+func TestLoggerWarnsOnFailedWrite(t *testing.T) {
+	verifyNoLeaks(t)
+	// A file where the cache directory should be makes every write fail
+	dir := filepath.Join(t.TempDir(), "cache")
+	if err := os.WriteFile(dir, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	cache := NewCache(time.Minute, WithDir(dir, time.Hour), WithLogger(logger))
+	defer cache.Close()
+	cache.Add("key", []byte("val"))
+	if err := cache.Flush(); err == nil {
+		t.Fatal("Flush() = nil, want an error")
+	}
+	if !strings.Contains(logs.String(), `level=WARN msg="cache write failed" key=key`) {
+		t.Errorf("logs after a failed flush = %q, want a warning", logs.String())
+	}
+}
@@ -0,0 +1,41 @@
+package pokecache
+
+import "time"
+
+//Remembers keys reported missing by AddMissing for ttl
+//Missing reports them until the ttl passes or a value is added for the key
+func WithNegativeTTL(ttl time.Duration) Option {
+    return func(c *Cache) {
+        c.negativeTTL = ttl
+    }
+}
+
+//Records that key has no response, such as a resource the API could not find
+//Does nothing unless WithNegativeTTL was given
+func (c *Cache) AddMissing(key string) {
+    if c.negativeTTL <= 0 {
+        return
+    }
+    sh := c.shardFor(key)
+    sh.mu.Lock()
+    defer sh.mu.Unlock()
+
+    sh.missing[key] = c.clock.Now()
+}
+
+//Reports whether key was recorded by AddMissing within the negative ttl
+//Positive answers are counted separately from hits in the stats
+func (c *Cache) Missing(key string) bool {
+    if c.negativeTTL <= 0 {
+        return false
+    }
+    sh := c.shardFor(key)
+    sh.mu.RLock()
+    at, found := sh.missing[key]
+    sh.mu.RUnlock()
+    if !found || c.clock.Now().Sub(at) > c.negativeTTL {
+        return false
+    }
+    sh.negativeHits.Add(1)
+    return true
+}
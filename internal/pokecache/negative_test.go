@@ -0,0 +1,73 @@
+package pokecache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMissingWithinTTL(t *testing.T) {
+	verifyNoLeaks(t)
+	clock := newFakeClock()
+	cache := NewCache(time.Minute, WithClock(clock), WithNegativeTTL(10*time.Second))
+	defer cache.Close()
+
+	cache.AddMissing("mt-moon")
+	if !cache.Missing("mt-moon") {
+		t.Fatalf("Missing after AddMissing = false, want true")
+	}
+	if cache.Missing("mt-coronet") {
+		t.Fatalf("Missing for an unrecorded key = true, want false")
+	}
+	clock.Advance(11 * time.Second)
+	if cache.Missing("mt-moon") {
+		t.Fatalf("Missing past the negative ttl = true, want false")
+	}
+
+	stats := cache.Stats()
+	if stats.NegativeHits != 1 || stats.Hits != 0 || stats.Misses != 0 {
+		t.Fatalf("Stats() = %+v, want only 1 negative hit", stats)
+	}
+}
+
+func TestAddClearsMissing(t *testing.T) {
+	verifyNoLeaks(t)
+	cache := NewCache(time.Minute, WithNegativeTTL(time.Minute))
+	defer cache.Close()
+
+	cache.AddMissing("key")
+	cache.Add("key", []byte("val"))
+	if cache.Missing("key") {
+		t.Fatalf("Missing after Add = true, want false")
+	}
+	if _, ok := cache.Get("key"); !ok {
+		t.Fatalf("Get after Add = miss, want hit")
+	}
+}
+
+func TestMissingDisabledWithoutTTL(t *testing.T) {
+	verifyNoLeaks(t)
+	cache := NewCache(time.Minute)
+	defer cache.Close()
+
+	cache.AddMissing("key")
+	if cache.Missing("key") || cache.Stats().NegativeEntries != 0 {
+		t.Fatalf("AddMissing without a negative ttl recorded the key")
+	}
+}
+
+func TestReaperRemovesMissing(t *testing.T) {
+	verifyNoLeaks(t)
+	clock := newFakeClock()
+	cache := NewCache(10*time.Second, WithClock(clock), WithShards(1), WithNegativeTTL(5*time.Second))
+	defer cache.Close()
+
+	cache.AddMissing("key")
+	clock.Advance(10 * time.Second)
+	deadline := time.Now().Add(time.Second)
+	for cache.Stats().NegativeEntries != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("reaper kept a missing record past the negative ttl")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -1,89 +1,452 @@
 package pokecache
 
 import (
+    "hash/maphash"
+    "log/slog"
+    "reflect"
+    "sort"
     "sync"
+    "sync/atomic"
     "time"
 )
 
 //Cached Entry response value and time
+//val holds gzip data when compressed is set; size is always the uncompressed length
+//createdAt is when the response was fetched and storedAt when it was put in memory;
+//they differ for entries read from the persistent tier or imported, whose interval starts once they are stored
 type cacheEntry struct {
     createdAt time.Time
+    storedAt time.Time
     val []byte
+    compressed bool
+    size int
+    sum uint64
 }
 
-//All cached values from responses
-type Cache struct {
+//Portion of the cached values guarded by its own lock
+//missing holds the time each key was recorded by AddMissing
+//Hit counters are kept per shard so lookups on different shards share no memory
+type shard struct {
     cachedValues map[string]cacheEntry
+    missing map[string]time.Time
     mu  *sync.RWMutex
+    hits *atomic.Int64
+    staleHits *atomic.Int64
+    diskHits *atomic.Int64
+    negativeHits *atomic.Int64
+    misses *atomic.Int64
+}
+
+//All cached values from responses, spread over shards by key hash
+//The reaper goroutine runs until Close is called
+type Cache struct {
+    shards []shard
+    interval time.Duration
+    quit chan struct{}
+    done chan struct{}
+    closeOnce *sync.Once
+    clock Clock
+    stale time.Duration
+    compressAt int
+    disk *diskTier
+    negativeTTL time.Duration
+    fills map[string]*fillCall
+    fillMu *sync.Mutex
+    refreshes *sync.WaitGroup
+    closing *atomic.Bool
+    typed map[reflect.Type]forgetter
+    typedMu *sync.Mutex
+    logger *slog.Logger
+}
+
+//Number of shards used unless WithShards is given
+const defaultShards = 16
+
+//Configures optional cache behaviour in NewCache
+type Option func(*Cache)
+
+//Spreads values over n independently locked shards
+//More shards reduce contention between goroutines using different keys
+func WithShards(n int) Option {
+    return func(c *Cache) {
+        if n < 1 {
+            n = 1
+        }
+        c.shards = newShards(n)
+    }
+}
+
+//Keeps values for window past the interval so they can be served stale
+//Get still misses on them; use Lookup to read stale values
+func WithStale(window time.Duration) Option {
+    return func(c *Cache) {
+        c.stale = window
+    }
+}
+
+//Uses clock for entry timestamps and the reaper ticker instead of wall time
+func WithClock(clock Clock) Option {
+    return func(c *Cache) {
+        c.clock = clock
+    }
 }
 
 //Interface for interacting with cache
 //Allows adding responses, retrieving responses
-//Will reap cached values older than 5 seconds
+//Will reap cached values older than the cache interval and stale window
 type storage interface {
     Add(key string, val []byte)
     Get(key string) (val []byte, found bool)
-    reapLoop(interval time.Duration)
+    Lookup(key string) (val []byte, fresh bool, found bool)
+    Close() error
 }
 
 //Generates a new cache map called _cache_storage
-//Makes a new async function to reap the cache of old reponses
-//Returns the cache map and error of creating reap function
-func NewCache(interval time.Duration) (Cache, chan bool) {
-    _cache_storage := Cache{
-        cachedValues: make(map[string]cacheEntry),
-        mu: &sync.RWMutex{},
+//Makes a new async function to reap the cache of reponses older than interval
+//An interval of zero or less keeps values forever and starts no reaper
+//Returns the cache map; the reaper is stopped by calling Close
+func NewCache(interval time.Duration, opts ...Option) *Cache {
+    _cache_storage := &Cache{
+        shards: newShards(defaultShards),
+        interval: interval,
+        quit: make(chan struct{}),
+        done: make(chan struct{}),
+        closeOnce: &sync.Once{},
+        clock: realClock{},
+        fills: make(map[string]*fillCall),
+        fillMu: &sync.Mutex{},
+        refreshes: &sync.WaitGroup{},
+        closing: &atomic.Bool{},
+        typed: make(map[reflect.Type]forgetter),
+        typedMu: &sync.Mutex{},
+    }
+    for _, opt := range opts {
+        opt(_cache_storage)
+    }
+    if interval <= 0 {
+        close(_cache_storage.done)
+        return _cache_storage
+    }
+    go _cache_storage.reapLoop(_cache_storage.clock.NewTicker(_cache_storage.reapPeriod()))
+    return _cache_storage
+}
+
+//Generates n empty shards
+func newShards(n int) []shard {
+    shards := make([]shard, n)
+    for i := range shards {
+        shards[i] = shard{
+            cachedValues: make(map[string]cacheEntry),
+            missing: make(map[string]time.Time),
+            mu: &sync.RWMutex{},
+            hits: &atomic.Int64{},
+            staleHits: &atomic.Int64{},
+            diskHits: &atomic.Int64{},
+            negativeHits: &atomic.Int64{},
+            misses: &atomic.Int64{},
+        }
+    }
+    return shards
+}
+
+//Returns the shard holding key
+func (c *Cache) shardFor(key string) *shard {
+    if len(c.shards) == 1 {
+        return &c.shards[0]
     }
-    quitChan := make(chan bool)
-    _cache_storage.reapLoop(5, quitChan)
-    return _cache_storage, quitChan
+    return &c.shards[maphash.String(hashSeed, key)%uint64(len(c.shards))]
 }
 
 //Adds a response to the cache
 //Requires the html reponse as a array of bytes, key as the API request
+//Compresses the response first if compression is enabled and it is large enough
+//The response is written to the persistent tier, if any, on the next Flush
 //Returns nothing
 func (c *Cache) Add(key string, val []byte) {
-    c.mu.Lock()
-    defer c.mu.Unlock()
+    c.addAt(key, val, c.clock.Now())
+}
+
+//Adds a response like Add, created at the time given rather than now
+//The entry is fresh for a full interval from now; the creation time is kept for the persistent tier and Export
+//A zero time is taken as now
+func (c *Cache) addAt(key string, val []byte, createdAt time.Time) {
+    if createdAt.IsZero() {
+        createdAt = c.clock.Now()
+    }
+    c.store(c.shardFor(key), key, c.newEntryAt(val, createdAt))
+    if c.disk != nil {
+        c.disk.markDirty(key)
+    }
+}
+
+//Stores the entry for key in its shard, clearing any record that it is missing
+func (c *Cache) store(sh *shard, key string, ce cacheEntry) {
+    sh.mu.Lock()
+    defer sh.mu.Unlock()
 
-    c.cachedValues[key] = cacheEntry{time.Now(), val}
+    sh.cachedValues[key] = ce
+    delete(sh.missing, key)
 }
 
 //Returns a response from the cache map if it exists
-//Returns an error if it is not contained
 //Requires the string API request as the key
+//Falls back to the persistent tier, if any, when the memory value is missing or expired
 //Returns the reponse if found and true
-//Returns no string and false if not found
+//Returns no string and false if not found or older than the interval
 func (c *Cache) Get(key string) (val []byte, found bool) {
-    c.mu.RLock()
-    defer c.mu.RUnlock()
+    ce, tier := c.lookup(key, true)
+    if !tier.fresh() {
+        return nil, false
+    }
+    return c.value(ce)
+}
+
+//Returns a response from the cache map, including stale ones
+//Falls back to the persistent tier, if any, when the memory value is missing or expired
+//Returns the reponse, true if it is within the interval and true if found
+//Values past the interval are found only within the WithStale window
+func (c *Cache) Lookup(key string) (val []byte, fresh bool, found bool) {
+    val, tier := c.LookupTier(key)
+    return val, tier.fresh(), tier != TierMiss
+}
+
+//Where a value returned by LookupTier was found
+type Tier int
+
+const (
+    //Not found, or found expired outside the stale window
+    TierMiss Tier = iota
+    //Fresh in memory
+    TierMemory
+    //Read from the persistent tier into memory
+    TierDisk
+    //Expired in memory but within the stale window
+    TierStale
+)
+
+//Returns the name of the tier: miss, memory, disk or stale
+func (t Tier) String() string {
+    switch t {
+    case TierMemory:
+        return "memory"
+    case TierDisk:
+        return "disk"
+    case TierStale:
+        return "stale"
+    default:
+        return "miss"
+    }
+}
+
+//Reports whether values from the tier are within the interval
+func (t Tier) fresh() bool {
+    return t == TierMemory || t == TierDisk
+}
+
+//Returns a response from the cache map like Lookup, along with the tier it was found in
+//The response is fresh unless the tier is TierStale
+func (c *Cache) LookupTier(key string) (val []byte, tier Tier) {
+    ce, tier := c.lookup(key, true)
+    if tier == TierMiss {
+        return nil, TierMiss
+    }
+    val, ok := c.value(ce)
+    if !ok {
+        return nil, TierMiss
+    }
+    return val, tier
+}
+
+//Returns the hash of the response held for key, without decompressing it
+//Matches the hash Typed computes for the same response
+//Returns false if not found or older than the interval
+//Not counted in the stats, as callers follow a changed hash with Get
+func (c *Cache) Sum(key string) (sum uint64, found bool) {
+    ce, tier := c.lookup(key, false)
+    return ce.sum, tier.fresh()
+}
+
+//Returns the entry for key and the tier it was found in, counting the result in the stats if count is set
+//A missing or expired memory entry is replaced by the persistent tier's entry for the key if it is no older
+//Entries on disk are fresh for the disk ttl; read back into memory, they are fresh for another interval
+func (c *Cache) lookup(key string, count bool) (ce cacheEntry, tier Tier) {
+    sh := c.shardFor(key)
+    counter := sh.misses
+    defer func() {
+        if count {
+            counter.Add(1)
+        }
+    }()
+    ce, fresh, found := c.entry(sh, key)
+    if found && fresh {
+        counter = sh.hits
+        return ce, TierMemory
+    }
+    if de, ok := c.readDisk(key); ok && (!found || !de.createdAt.Before(ce.createdAt)) {
+        c.store(sh, key, de)
+        counter = sh.diskHits
+        return de, TierDisk
+    }
+    if found {
+        counter = sh.staleHits
+        return ce, TierStale
+    }
+    return ce, TierMiss
+}
+
+//Reads the entry for key from the persistent tier, if any, keeping the creation time it was written with
+//Returns false if it has no entry for key within the disk ttl
+func (c *Cache) readDisk(key string) (cacheEntry, bool) {
+    if c.disk == nil {
+        return cacheEntry{}, false
+    }
+    be, ok := c.disk.read(key, c.clock.Now())
+    if !ok {
+        return cacheEntry{}, false
+    }
+    ce := c.newEntryAt(be.Value, be.CreatedAt)
+    c.log().Debug("cache read from disk", "key", key)
+    return ce, true
+}
+
+//Returns the keys of every value in memory within the interval in sorted order
+func (c *Cache) Keys() []string {
+    now := c.clock.Now()
+    keys := make([]string, 0)
+    for i := range c.shards {
+        sh := &c.shards[i]
+        sh.mu.RLock()
+        for key, ce := range sh.cachedValues {
+            if !c.expired(ce, now) {
+                keys = append(keys, key)
+            }
+        }
+        sh.mu.RUnlock()
+    }
+    sort.Strings(keys)
+    return keys
+}
+
+//Returns the entry for key from its shard and whether it is within the interval
+//Entries past the stale window are not found
+func (c *Cache) entry(sh *shard, key string) (ce cacheEntry, fresh bool, found bool) {
+    sh.mu.RLock()
+    defer sh.mu.RUnlock()
+
+    ce, found = sh.cachedValues[key]
+    now := c.clock.Now()
+    if !found || c.reapable(ce, now) {
+        return cacheEntry{}, false, false
+    }
+    return ce, !c.expired(ce, now), true
+}
 
-    ce, found := c.cachedValues[key]
-    return ce.val, found
+//Reports the number of values held, including expired ones not yet reaped
+func (c *Cache) Len() int {
+    n := 0
+    for i := range c.shards {
+        sh := &c.shards[i]
+        sh.mu.RLock()
+        n += len(sh.cachedValues)
+        sh.mu.RUnlock()
+    }
+    return n
+}
+
+//Stops the reaper, waits for it and any refreshes in progress to finish and flushes the persistent tier
+//Values decoded through TypedFor are dropped
+//Safe to call more than once; later calls only flush values added since
+//Cached values remain readable after Close
+func (c *Cache) Close() error {
+    c.fillMu.Lock()
+    c.closing.Store(true)
+    c.fillMu.Unlock()
+    c.refreshes.Wait()
+    c.closeOnce.Do(func() {
+        close(c.quit)
+        <-c.done
+    })
+    c.forget()
+    return c.Flush()
 }
 
-//Asyncronous loop responsible for pruning the cache map for old values
-//On every ticker tick, compares the time to the maximum interval
-//If the interval is greater, than the value is removed
-func (c *Cache) reapLoop(interval time.Duration, quit chan bool) {
-    ticker := time.NewTicker(time.Second)
+//Loop responsible for pruning the cache map for old values
+//On every ticker tick, reaps the next shard so only one shard is locked at a time
+//Every shard is visited once per interval
+//The persistent tier is flushed at the start of each pass so values are written before they are reaped
+//Returns once Close is called
+func (c *Cache) reapLoop(ticker Ticker) {
+    defer close(c.done)
     defer ticker.Stop()
-    go func() {
-        for {
-            select {
-            case <- quit:
-                return
-            case <-ticker.C:
-                c.mu.Lock()
-                for val := range(c.cachedValues) {
-                    now := time.Now()
-                    if  now.Sub(c.cachedValues[val].createdAt) > interval {
-                        delete(c.cachedValues, val)
-                    }
-                }
-                c.mu.Unlock()
+    next := 0
+    for {
+        select {
+        case <-c.quit:
+            return
+        case now := <-ticker.Chan():
+            if next == 0 {
+                c.Flush()
             }
+            c.reapShard(&c.shards[next], now)
+            next = (next + 1) % len(c.shards)
         }
-    }()
+    }
+}
+
+//Returns the reaper tick period that visits every shard once per interval
+func (c *Cache) reapPeriod() time.Duration {
+    period := c.interval / time.Duration(len(c.shards))
+    if period <= 0 {
+        return 1
+    }
+    return period
+}
+
+//Removes every value older than the interval and stale window at the time now
+func (c *Cache) reap(now time.Time) {
+    for i := range c.shards {
+        c.reapShard(&c.shards[i], now)
+    }
 }
 
+//Removes every value in the shard older than the interval and stale window at the time now
+//along with the values decoded from them through TypedFor
+//Also removes missing records older than the negative ttl
+func (c *Cache) reapShard(sh *shard, now time.Time) {
+    reaped := c.reapValues(sh, now)
+    if len(reaped) > 0 {
+        c.log().Debug("cache values reaped", "count", len(reaped))
+        c.forget(reaped...)
+    }
+}
+
+//Removes the shard's values and missing records that have expired at the time now
+//Returns the keys of the values removed
+func (c *Cache) reapValues(sh *shard, now time.Time) []string {
+    sh.mu.Lock()
+    defer sh.mu.Unlock()
+    var reaped []string
+    for val := range(sh.cachedValues) {
+        if c.reapable(sh.cachedValues[val], now) {
+            delete(sh.cachedValues, val)
+            reaped = append(reaped, val)
+        }
+    }
+    for key, at := range sh.missing {
+        if now.Sub(at) > c.negativeTTL {
+            delete(sh.missing, key)
+        }
+    }
+    return reaped
+}
+
+//Reports whether the entry has been in memory longer than the interval at the time now
+//Entries never expire when the interval is zero or less
+func (c *Cache) expired(ce cacheEntry, now time.Time) bool {
+    return c.interval > 0 && now.Sub(ce.storedAt) > c.interval
+}
+
+//Reports whether the entry is past both the interval and stale window at the time now
+func (c *Cache) reapable(ce cacheEntry, now time.Time) bool {
+    return c.interval > 0 && now.Sub(ce.storedAt) > c.interval+c.stale
+}
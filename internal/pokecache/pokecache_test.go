@@ -1,25 +1,70 @@
 package pokecache
 
 import (
-	"reflect"
-	"sync"
+	"os"
+	"runtime"
+	"strings"
 	"testing"
     "fmt"
     "time"
 )
 
+//Fails the package if a reaper goroutine outlives the tests
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if code == 0 {
+		if leaked := leakedReapers(time.Second); leaked != "" {
+			fmt.Fprintf(os.Stderr, "leaked goroutines after tests:\n%s\n", leaked)
+			code = 1
+		}
+	}
+	os.Exit(code)
+}
+
+//Returns the stacks of any goroutines still running reapLoop
+//Retries until wait elapses so goroutines that are exiting can finish
+func leakedReapers(wait time.Duration) string {
+	deadline := time.Now().Add(wait)
+	for {
+		buf := make([]byte, 1<<20)
+		buf = buf[:runtime.Stack(buf, true)]
+		var leaked []string
+		for _, g := range strings.Split(string(buf), "\n\n") {
+			if strings.Contains(g, "pokecache.(*Cache).reapLoop") {
+				leaked = append(leaked, g)
+			}
+		}
+		if len(leaked) == 0 || time.Now().After(deadline) {
+			return strings.Join(leaked, "\n\n")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+//Registers a check that no reaper survives the calling test
+func verifyNoLeaks(t *testing.T) {
+	t.Helper()
+	t.Cleanup(func() {
+		if leaked := leakedReapers(time.Second); leaked != "" {
+			t.Errorf("reaper goroutine leaked:\n%s", leaked)
+		}
+	})
+}
+
 func TestCreateCache(t *testing.T) {
-	_test_cache, err := NewCache(7)
-	want := Cache{
-		cachedValues: make(map[string]cacheEntry),
-		mu:           &sync.RWMutex{},
+	verifyNoLeaks(t)
+	_test_cache := NewCache(7 * time.Second)
+	defer _test_cache.Close()
+	if len(_test_cache.shards) != defaultShards || _test_cache.Len() != 0 {
+		t.Fatalf(`NewCache(7s) has %d shards and %d values, want %d empty shards`, len(_test_cache.shards), _test_cache.Len(), defaultShards)
 	}
-	if !(reflect.DeepEqual(want, _test_cache) || err != nil) {
-		t.Fatalf(`NewCache(7) = %v, %v, want match for %v, nil`, _test_cache, err, want)
+	if _test_cache.interval != 7*time.Second {
+		t.Fatalf(`NewCache(7s) interval = %v, want 7s`, _test_cache.interval)
 	}
 }
 
 func TestAddGet(t *testing.T) {
+	verifyNoLeaks(t)
 	const interval = 5 * time.Second
 	cases := []struct {
 		key string
@@ -36,7 +81,8 @@ func TestAddGet(t *testing.T) {
 	}
 	for i, c := range cases {
 		t.Run(fmt.Sprintf("Test case %v", i), func(T *testing.T) {
-			cache,_ := NewCache(interval)
+			cache := NewCache(interval)
+			defer cache.Close()
 			cache.Add(c.key, c.val)
 			val, ok := cache.Get(c.key)
 			if !ok {
@@ -50,3 +96,51 @@ func TestAddGet(t *testing.T) {
 		})
 	}
 }
+
+func TestCloseStopsReaper(t *testing.T) {
+	verifyNoLeaks(t)
+	cache := NewCache(time.Millisecond)
+	if err := cache.Close(); err != nil {
+		t.Fatalf("Close() = %v, want nil", err)
+	}
+	select {
+	case <-cache.done:
+	default:
+		t.Fatalf("reaper still running after Close")
+	}
+}
+
+func TestCloseIdempotent(t *testing.T) {
+	verifyNoLeaks(t)
+	cache := NewCache(time.Second)
+	for i := 0; i < 3; i++ {
+		if err := cache.Close(); err != nil {
+			t.Fatalf("Close() call %d = %v, want nil", i, err)
+		}
+	}
+}
+
+func TestCloseConcurrent(t *testing.T) {
+	verifyNoLeaks(t)
+	cache := NewCache(time.Second)
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		go func() { errs <- cache.Close() }()
+	}
+	for i := 0; i < 8; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("Close() = %v, want nil", err)
+		}
+	}
+}
+
+func TestGetAfterClose(t *testing.T) {
+	verifyNoLeaks(t)
+	cache := NewCache(time.Second)
+	cache.Add("key", []byte("val"))
+	cache.Close()
+	val, ok := cache.Get("key")
+	if !ok || string(val) != "val" {
+		t.Fatalf(`Get("key") after Close = %q, %v, want "val", true`, val, ok)
+	}
+}
@@ -0,0 +1,135 @@
+package pokecache
+
+import (
+    "encoding/json"
+    "hash/maphash"
+    "reflect"
+    "sync"
+)
+
+//Decoded values keyed by resource
+//Saves re-running json.Unmarshal on raw bodies that have already been decoded
+//A value is reused only while the raw body passed to Decode is unchanged
+//Returned values are shared between callers and must be treated as read-only
+type Typed[T any] struct {
+    values map[string]typedEntry[T]
+    mu *sync.RWMutex
+}
+
+//Returns the decoded value cache for T that belongs to c, creating it on first use
+//A decoded value is forgotten when the reaper removes the response it came from, and every one when c is closed,
+//so decoded values live no longer than the responses they were decoded from
+func TypedFor[T any](c *Cache) *Typed[T] {
+    kind := reflect.TypeOf((*T)(nil)).Elem()
+    c.typedMu.Lock()
+    defer c.typedMu.Unlock()
+
+    if t, found := c.typed[kind]; found {
+        return t.(*Typed[T])
+    }
+    t := NewTyped[T]()
+    c.typed[kind] = t
+    return t
+}
+
+//Decoded value cache of any type, as held by the cache its values came from
+type forgetter interface {
+    Forget(key string)
+    forgetAll()
+}
+
+//Drops the decoded values for keys from every decoded value cache belonging to c
+//With no keys, drops every decoded value
+//Does nothing for a Cache not made by NewCache, which has none
+func (c *Cache) forget(keys ...string) {
+    if c.typedMu == nil {
+        return
+    }
+    c.typedMu.Lock()
+    defer c.typedMu.Unlock()
+
+    for _, t := range c.typed {
+        if len(keys) == 0 {
+            t.forgetAll()
+        }
+        for _, key := range keys {
+            t.Forget(key)
+        }
+    }
+}
+
+//Decoded value and the hash of the raw body it came from
+type typedEntry[T any] struct {
+    sum uint64
+    val T
+}
+
+//Generates a new empty decoded value cache
+func NewTyped[T any]() *Typed[T] {
+    return &Typed[T]{
+        values: make(map[string]typedEntry[T]),
+        mu: &sync.RWMutex{},
+    }
+}
+
+//Returns the decoded value of raw for the resource key
+//Reuses the stored value if raw matches the body it was decoded from
+//Otherwise unmarshals raw, stores the result and returns it
+func (t *Typed[T]) Decode(key string, raw []byte) (T, error) {
+    sum := maphash.Bytes(hashSeed, raw)
+    t.mu.RLock()
+    te, found := t.values[key]
+    t.mu.RUnlock()
+    if found && te.sum == sum {
+        return te.val, nil
+    }
+
+    var val T
+    if err := json.Unmarshal(raw, &val); err != nil {
+        return val, err
+    }
+    t.mu.Lock()
+    t.values[key] = typedEntry[T]{sum, val}
+    t.mu.Unlock()
+    return val, nil
+}
+
+//Returns the decoded value of the response held in c for key
+//Reuses the stored value without reading c's response if the response is unchanged
+//Returns false if c has no response within its interval for key
+func (t *Typed[T]) DecodeFrom(c *Cache, key string) (T, bool, error) {
+    var val T
+    sum, found := c.Sum(key)
+    if !found {
+        return val, false, nil
+    }
+    t.mu.RLock()
+    te, found := t.values[key]
+    t.mu.RUnlock()
+    if found && te.sum == sum {
+        return te.val, true, nil
+    }
+
+    raw, found := c.Get(key)
+    if !found {
+        return val, false, nil
+    }
+    val, err := t.Decode(key, raw)
+    return val, true, err
+}
+
+//Drops the decoded value for the resource key
+func (t *Typed[T]) Forget(key string) {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+
+    delete(t.values, key)
+}
+
+//Drops every decoded value
+func (t *Typed[T]) forgetAll() {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+
+    t.values = make(map[string]typedEntry[T])
+}
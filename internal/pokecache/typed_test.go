@@ -0,0 +1,108 @@
+package pokecache
+
+import (
+	"testing"
+	"time"
+)
+
+type typedTestValue struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func TestTypedDecodeReusesValue(t *testing.T) {
+	typed := NewTyped[*typedTestValue]()
+	raw := []byte(`{"name":"pikachu","count":1}`)
+	first, err := typed.Decode("pikachu", raw)
+	if err != nil {
+		t.Fatalf("Decode = %v, want nil error", err)
+	}
+	second, err := typed.Decode("pikachu", append([]byte(nil), raw...))
+	if err != nil {
+		t.Fatalf("Decode = %v, want nil error", err)
+	}
+	if first != second {
+		t.Fatalf("Decode of an unchanged body decoded again, want the stored value")
+	}
+}
+
+func TestTypedDecodeChangedBody(t *testing.T) {
+	typed := NewTyped[typedTestValue]()
+	if _, err := typed.Decode("key", []byte(`{"name":"old"}`)); err != nil {
+		t.Fatalf("Decode = %v, want nil error", err)
+	}
+	val, err := typed.Decode("key", []byte(`{"name":"new"}`))
+	if err != nil || val.Name != "new" {
+		t.Fatalf(`Decode of a changed body = %+v, %v, want name "new"`, val, err)
+	}
+}
+
+func TestTypedDecodeError(t *testing.T) {
+	typed := NewTyped[typedTestValue]()
+	if _, err := typed.Decode("key", []byte(`{"name":`)); err == nil {
+		t.Fatalf("Decode of invalid JSON = nil error, want error")
+	}
+	if len(typed.values) != 0 {
+		t.Fatalf("Decode stored a value for invalid JSON")
+	}
+}
+
+func TestTypedForget(t *testing.T) {
+	typed := NewTyped[*typedTestValue]()
+	raw := []byte(`{"name":"pikachu"}`)
+	first, _ := typed.Decode("key", raw)
+	typed.Forget("key")
+	second, _ := typed.Decode("key", raw)
+	if first == second {
+		t.Fatalf("Decode after Forget returned the forgotten value")
+	}
+}
+
+func TestTypedDecodeFromCompressedCache(t *testing.T) {
+	cache := NewCache(0, WithCompression(1))
+	defer cache.Close()
+	cache.Add("pikachu", []byte(`{"name":"pikachu","count":1,"padding":"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"}`))
+
+	typed := NewTyped[*typedTestValue]()
+	first, found, err := typed.DecodeFrom(cache, "pikachu")
+	if err != nil || !found || first.Name != "pikachu" {
+		t.Fatalf("DecodeFrom = %+v, %v, %v, want pikachu", first, found, err)
+	}
+	hits := cache.Stats().Hits
+	second, _, _ := typed.DecodeFrom(cache, "pikachu")
+	if first != second {
+		t.Fatalf("DecodeFrom of an unchanged response decoded again")
+	}
+	if cache.Stats().Hits != hits {
+		t.Fatalf("DecodeFrom read the response although the decoded value was current")
+	}
+	if _, found, _ := typed.DecodeFrom(cache, "missing"); found {
+		t.Fatalf("DecodeFrom of a missing key = found, want not found")
+	}
+}
+
+func TestTypedForForgetsWithTheCache(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewCache(time.Minute, WithClock(clock))
+	typed := TypedFor[*typedTestValue](cache)
+	if TypedFor[*typedTestValue](cache) != typed {
+		t.Fatalf("TypedFor returned a new decoded value cache for the same type")
+	}
+	cache.Add("pikachu", []byte(`{"name":"pikachu"}`))
+	if _, found, err := typed.DecodeFrom(cache, "pikachu"); !found || err != nil {
+		t.Fatalf("DecodeFrom = %v, %v, want found", found, err)
+	}
+
+	clock.Advance(2 * time.Minute)
+	cache.reap(clock.Now())
+	if len(typed.values) != 0 {
+		t.Fatalf("%d decoded values kept after their responses were reaped, want 0", len(typed.values))
+	}
+
+	cache.Add("pikachu", []byte(`{"name":"pikachu"}`))
+	typed.DecodeFrom(cache, "pikachu")
+	cache.Close()
+	if len(typed.values) != 0 {
+		t.Fatalf("%d decoded values kept after Close, want 0", len(typed.values))
+	}
+}
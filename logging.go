@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"internal/pokeapi"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// Receives the records of pokeapi and the caches, set up by setupLogger
+var logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+// Sends log records at the log_level setting and above to log_file, or to stderr when it is empty
+// Results on stdout never carry log records
+func setupLogger() error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.get("log_level"))); err != nil {
+		return err
+	}
+	var w io.Writer = os.Stderr
+	if path := cfg.get("log_file"); path != "" {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("log file: %w\n", err)
+		}
+		w = f
+	}
+	logger = slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: level}))
+	pokeapi.Logger = logger
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"internal/pokeapi"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetupLoggerWritesToLogFile(t *testing.T) {
+	useConfigFile(t, "")
+	oldLogger, oldAPILogger := logger, pokeapi.Logger
+	defer func() { logger, pokeapi.Logger = oldLogger, oldAPILogger }()
+	path := filepath.Join(t.TempDir(), "pokedex.log")
+	args, err := loadConfig([]string{"-vv", "--log-file", path, "pokedex"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(args) != 1 || cfg.get("log_level") != "debug" {
+		t.Fatalf("args %q and log_level %s, want pokedex and debug", args, cfg.get("log_level"))
+	}
+	if err := setupLogger(); err != nil {
+		t.Fatal(err)
+	}
+	pokeapi.Logger.Debug("cache hit", "url", "pokemon/mew")
+	pokeapi.Logger.Info("request")
+
+	cfg.set("log_level", "warn", "flag")
+	if err := setupLogger(); err != nil {
+		t.Fatal(err)
+	}
+	pokeapi.Logger.Info("dropped")
+	pokeapi.Logger.Warn("cache write failed")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	want := []string{`level=DEBUG msg="cache hit" url=pokemon/mew`, `level=INFO msg=request`, `level=WARN msg="cache write failed"`}
+	if len(lines) != len(want) {
+		t.Fatalf("log file =\n%s\nwant %d records", data, len(want))
+	}
+	for i, line := range lines {
+		if !strings.HasSuffix(line, want[i]) {
+			t.Errorf("record %d = %q, want it to end with %q", i, line, want[i])
+		}
+	}
+}
+
+func TestVerbosityFlags(t *testing.T) {
+	for _, c := range []struct{ flag, level string }{{"-v", "info"}, {"-vv", "debug"}} {
+		flags, rest, err := parseGlobalFlags([]string{c.flag, "map"})
+		if err != nil || flags["log_level"] != c.level || len(rest) != 1 {
+			t.Errorf("%s: flags %v, rest %q, err %v, want log_level %s", c.flag, flags, rest, err, c.level)
+		}
+	}
+}
@@ -1,193 +1,425 @@
 package main
 
 import (
-	"bufio"
 	"fmt"
+	"internal/lineedit"
 	"internal/pokeapi"
 	"internal/pokecache"
+	"io"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 )
 
-type cliCommand struct {
-	name        string
-	description string
-	callback    func(string) error
-}
-
-var quickMap map[string]cliCommand
-
-// Creates a map of the possible CLI commands and their associated functions
-func makeCommandMap() {
-	quickMap = map[string]cliCommand{
-		"help": {
-			name:        "help",
-			description: "Displays a help message",
-			callback:    commandHelp,
-		},
-		"exit": {
-			name:        "exit",
-			description: "Exit the Pokedex",
-			callback:    commandExit,
-		},
-		"map": {
-			name:        "map",
-			description: "Displays the names of the next 20 location areas in the Pokemon World",
-			callback:    displayMap,
-		},
-		"mapb": {
-			name:        "mapb",
-			description: "Displays the names of the previous 20 location areas in the Pokemon World",
-			callback:    displayMapBack,
+func init() {
+	registry.register(cliCommand{
+		name:        "help",
+		aliases:     []string{"?"},
+		description: "Displays a help message",
+		long:        "Without arguments, lists every command by category. With a command, shows its usage, flags and examples.",
+		args:        []argSpec{{name: "command", optional: true, variadic: true, complete: func(*repl) []string { return commandNames() }}},
+		examples:    []string{"help", "help catch", "help cache export"},
+		callback:    (*repl).commandHelp,
+	})
+	registry.register(cliCommand{
+		name:        "exit",
+		aliases:     []string{"quit"},
+		description: "Exit the Pokedex",
+		callback:    (*repl).commandExit,
+	})
+	registry.register(cliCommand{
+		name:        "map",
+		category:    "Exploring",
+		description: "Displays the names of the next 20 location areas in the Pokemon World",
+		flags:       []flagSpec{outputFlag},
+		callback:    (*repl).displayMap,
+	})
+	registry.register(cliCommand{
+		name:        "mapb",
+		category:    "Exploring",
+		description: "Displays the names of the previous 20 location areas in the Pokemon World",
+		flags:       []flagSpec{outputFlag},
+		callback:    (*repl).displayMapBack,
+	})
+	registry.register(cliCommand{
+		name:        "explore",
+		category:    "Exploring",
+		description: "Displays the names of the pokemon in a specific area",
+		long:        "Area names are the ones listed by map and mapb.",
+		args:        []argSpec{{name: "area", complete: func(r *repl) []string { return r.lastMapPage }}},
+		examples:    []string{"explore canalave-city-area"},
+		flags:       []flagSpec{outputFlag},
+		callback:    (*repl).exploreArea,
+	})
+	registry.register(cliCommand{
+		name:        "catch",
+		category:    "Pokemon",
+		description: "Attempts to catch a specfic pokemon",
+		long:        "The chance of a catch rises with the species' capture rate, so rare pokemon such as legendaries are hard to catch. Caught pokemon are added to your pokedex.",
+		args:        []argSpec{{name: "pokemon", complete: func(r *repl) []string { return r.lastExplored }}},
+		examples:    []string{"catch pikachu"},
+		flags:       []flagSpec{outputFlag},
+		callback:    (*repl).catchPokemon,
+	})
+	registry.register(cliCommand{
+		name:        "inspect",
+		category:    "Pokemon",
+		description: "Attempts to inspect a caught pokemon",
+		long: "On a terminal, text output starts with the pokemon's sprite, drawn in color where the terminal allows it and in ASCII otherwise.\n" +
+			"Output piped or redirected to a file leaves it out unless --sprite is given.",
+		args:     []argSpec{{name: "pokemon", complete: (*repl).caughtPokemon}},
+		examples: []string{"inspect pikachu", "inspect --no-sprite pikachu", "inspect --sprite pikachu > pikachu.txt"},
+		flags: []flagSpec{
+			outputFlag,
+			{name: "sprite", usage: "draw the sprite even when output is not a terminal", boolean: true},
+			{name: "no-sprite", usage: "leave out the sprite", boolean: true},
 		},
-		"explore": {
-			name:        "explore",
-			description: "Displays the names of the pokemon in a specific area",
-			callback:    exploreArea,
-		},
-		"catch": {
-			name:        "catch",
-			description: "Attempts to catch a specfic pokemon",
-			callback:    catchPokemon,
-		},
-		"inspect": {
-			name:        "inspect",
-			description: "Attempts to inspect a caught pokemon",
-			callback:    inspectPokemon,
-		},
-		"pokedex": {
-			name:        "pokedex",
-			description: "List all captured pokemon in your pokedex",
-			callback:    explorePokedex,
+		callback: (*repl).inspectPokemon,
+	})
+	registry.register(cliCommand{
+		name:        "pokedex",
+		aliases:     []string{"dex"},
+		category:    "Pokemon",
+		description: "List all captured pokemon in your pokedex",
+		flags:       []flagSpec{outputFlag},
+		callback:    (*repl).explorePokedex,
+	})
+	registry.register(cliCommand{
+		name:        "cache",
+		category:    "Cache",
+		description: "Manages the response cache",
+		long:        "Without a subcommand, prints the cache stats.",
+		examples:    []string{"cache stats", "cache export kanto.bundle", "cache import kanto.bundle"},
+		callback:    (*repl).cacheStats,
+		subcommands: []cliCommand{
+			{
+				name:        "stats",
+				description: "Prints entry counts, hit rates and the compression ratio of the response cache and pokedex",
+				callback:    (*repl).cacheStats,
+			},
+			{
+				name:        "export",
+				description: "Writes the response cache to a bundle file",
+				args:        []argSpec{{name: "file"}},
+				callback:    (*repl).exportCache,
+			},
+			{
+				name:        "import",
+				description: "Adds the responses in a bundle file to the response cache",
+				args:        []argSpec{{name: "file"}},
+				callback:    (*repl).importCache,
+			},
 		},
+	})
+}
+
+// Creates the session of this process, reading stdin and writing stdout
+// The response cache persists to cache_dir unless it is "off", and is seeded from offline_bundle when set
+// The pokedex is loaded from pokedex_file unless it is "off"; if the file cannot be read it is not saved over either
+func setupSession() *repl {
+	options := []pokecache.Option{
+		pokecache.WithLogger(logger.With("cache", "responses")),
+		pokecache.WithStale(cfg.duration("stale_window")),
+		pokecache.WithCompression(1024),
+		pokecache.WithNegativeTTL(cfg.duration("negative_ttl")),
 	}
+	if dir := cfg.get("cache_dir"); dir != "" && dir != "off" {
+		options = append(options, pokecache.WithDir(dir, cfg.duration("disk_ttl")))
+	}
+	cache := pokecache.NewCache(cfg.duration("cache_ttl"), options...)
+	pokedex := pokecache.NewCache(0, pokecache.WithCompression(1024), pokecache.WithLogger(logger.With("cache", "pokedex")))
+	r := newREPL(os.Stdin, os.Stdout, os.Stderr, cache, pokedex)
+	r.api.StaleWhileRevalidate = true
+	r.api.StaleIfError = true
+	if file := cfg.get("pokedex_file"); file != "" && file != "off" {
+		if err := loadPokedex(pokedex, file); err != nil {
+			fmt.Fprintf(r.errOut, "pokedex not loaded, and will not be saved: %s", errorText(err))
+		} else {
+			r.pokedexFile = file
+		}
+	}
+	if bundle := cfg.get("offline_bundle"); bundle != "" {
+		if err := r.importBundle(bundle); err != nil {
+			fmt.Fprintf(r.errOut, "offline bundle: %s", errorText(err))
+		}
+	}
+	return r
 }
 
-var _cached_storage pokecache.Cache
-var _pokedex_storage map[string][]byte
-var _quit_channel chan bool
+// Subcommands run by "pokedexcli <name> ..." instead of the input loop
+// Each returns the process exit code
+var subcommands = map[string]func(r *repl, args []string) int{
+	"prefetch": runPrefetch,
+	"proxy":    runProxy,
+	"tui":      runTUI,
+}
 
 // Entry point | runs main input loop
+// "pokedexcli <subcommand> ..." runs the subcommand and exits instead,
+// and "pokedexcli <command> [args]" runs a single REPL command
+// Commands piped into stdin run as a script without the prompt
+// However the session ends, by exit, the end of input or SIGINT or SIGTERM, the pokedex is saved and the caches flushed
 func main() {
-	cached, quitChan := pokecache.NewCache(30)
-	_pokedex_storage = make(map[string][]byte)
-	_cached_storage = cached
-	_quit_channel = quitChan
-	scanner := bufio.NewScanner(os.Stdin)
-	writer := bufio.NewWriter(os.Stdout)
-	text := "pokedex > "
-	fmt.Fprint(writer, text)
-	writer.Flush()
-	for scanner.Scan() {
-		text += scanner.Text()
-		parseCLiCommand(text)
-		text = "pokedex > "
-		fmt.Fprint(writer, text)
-		writer.Flush()
-	}
-}
-
-// Function to parse input from user and run command if found
-// Commands are stored in the command map
-// If command is not found, does nothing
-// If command is found, calls the callback function | All callback functions don't require any arguments
-func parseCLiCommand(input string) {
-	input = strings.ToLower(input)
-	if strings.Contains(input, "pokedex > ") {
-		makeCommandMap()
-		commandString, found := strings.CutPrefix(input, "pokedex > ")
-		commandString, arguments, _ := strings.Cut(commandString, " ")
-		if found {
-			command, ok := quickMap[commandString]
-			if !ok {
-				fmt.Printf("Encountered error\n")
-				return
-			}
-			fmt.Println("Executing")
-			err := command.callback(arguments)
-			if err != nil {
-				fmt.Printf("Encountered error: %s", err)
-				return
-			}
+	args, err := loadConfig(os.Args[1:])
+	if err != nil {
+		os.Exit(exitCode(os.Stderr, err))
+	}
+	if err := setupLogger(); err != nil {
+		os.Exit(exitCode(os.Stderr, err))
+	}
+	for _, err := range cfg.warnings {
+		logger.Warn("config file not fully read, using defaults", "err", strings.TrimSpace(err.Error()))
+	}
+	r := setupSession()
+	// prefetch and proxy stop on signals themselves, and tui reads Ctrl-C as a key
+	if len(args) == 0 || subcommands[args[0]] == nil {
+		r.handleSignals()
+	}
+	var code int
+	switch {
+	case len(args) > 0:
+		code = r.runArgs(args)
+	case !lineedit.IsTerminal(int(os.Stdin.Fd())):
+		code = exitCode(r.errOut, r.runScript("stdin", os.Stdin))
+	default:
+		code = exitCode(r.errOut, r.Run())
+	}
+	if err := r.shutdown(); err != nil && code == exitOK {
+		code = exitCode(r.errOut, err)
+	}
+	os.Exit(code)
+}
+
+// Number of lines kept in the REPL history
+const historySize = 1000
+
+// Loads the REPL history kept in the user state directory
+// Falls back to a history for this session only if the file cannot be read
+func loadHistory() *lineedit.History {
+	if dir, err := userDir("XDG_STATE_HOME", ".local/state"); err == nil {
+		if history, err := lineedit.LoadHistory(filepath.Join(dir, "pokedexcli", "history"), historySize); err == nil {
+			return history
 		}
 	}
+	return lineedit.NewHistory(historySize)
+}
+
+// Returns the error message ending in exactly one newline
+// Errors from this package carry their own newline but those from pokeapi do not
+func errorText(err error) string {
+	return strings.TrimRight(err.Error(), "\n") + "\n"
+}
+
+// Splits a line of input into words like a shell would and runs the command they name
+// Blank lines and lines starting with # do nothing
+func (r *repl) runLine(input string) error {
+	if strings.HasPrefix(strings.TrimSpace(input), "#") {
+		return nil
+	}
+	words, err := splitArgs(input)
+	if err != nil {
+		return err
+	}
+	if len(words) == 0 {
+		return nil
+	}
+	return r.runWords(words)
 }
 
-// Prints the name of the command and its description held in the map
-func commandHelp(arguments string) error {
-	fmt.Printf("Welcome to the CLI Pokedex!\nUsage:\n\n")
-	for msg := range quickMap {
-		fmt.Printf("%v : %v\n", quickMap[msg].name, quickMap[msg].description)
+// Runs the command named by the leading words with the rest as its arguments and flags
+// Commands are looked up in the registry by name or alias; names are not case sensitive
+// Returns a usageError for unknown commands or input not matching the command's arguments
+func (r *repl) runWords(words []string) error {
+	input := strings.Join(words, " ")
+	command, words, err := registry.resolve(words)
+	if err != nil {
+		return err
+	}
+	arguments, err := parseArgs(*command, words)
+	if err != nil {
+		return err
+	}
+	if r.interactive {
+		fmt.Fprintln(r.out, "Executing")
+	}
+	if !command.untraced {
+		defer r.startTrace(input)()
+	}
+	r.api.ResetStale()
+	if err := command.callback(r, arguments); err != nil {
+		return err
+	}
+	if r.api.ServedStale() {
+		fmt.Fprintln(r.errOut, "(some results came from stale cached data)")
 	}
 	return nil
 }
 
-// Exits the CLI application
-func commandExit(arguments string) error {
-	os.Exit(0)
+// Prints every command grouped by category, or the detailed usage of the named command
+func (r *repl) commandHelp(arguments commandArgs) error {
+	if len(arguments.Rest()) == 0 {
+		registry.writeHelp(r.out)
+		return nil
+	}
+	command, rest, err := registry.find(arguments.Rest())
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("%s has no subcommand %q\n", command.path, rest[0])
+	}
+	command.writeHelp(r.out)
 	return nil
+}
 
+// Ends the session
+func (r *repl) commandExit(arguments commandArgs) error {
+	return errExit
 }
 
 // Requests the next area of the map and displays it if found
 // Moves the request forward if area has already been requested
-func displayMap(arguments string) error {
-	areas, err := pokeapi.GetAreaLocation(1, &_cached_storage)
+func (r *repl) displayMap(arguments commandArgs) error {
+	areas, err := r.api.GetAreaLocation(1, r.cache)
 	if err != nil {
 		return err
 	}
-	for _, area := range areas {
-		fmt.Printf("%v\n", area)
-	}
-	return nil
+	r.lastMapPage = areas
+	return r.emit(arguments, mapResult{Command: "map", Areas: areas})
 }
 
 // Requests the previous area of the map and displays it if found
 // Moves the request backward if area has already been requested
-func displayMapBack(arguments string) error {
-	areas, err := pokeapi.GetAreaLocation(-1, &_cached_storage)
+func (r *repl) displayMapBack(arguments commandArgs) error {
+	areas, err := r.api.GetAreaLocation(-1, r.cache)
 	if err != nil {
 		return err
 	}
-	for _, area := range areas {
-		fmt.Printf("%v\n", area)
+	r.lastMapPage = areas
+	return r.emit(arguments, mapResult{Command: "mapb", Areas: areas})
+}
+func (r *repl) exploreArea(arguments commandArgs) error {
+	encounters, err := r.api.Explore(strings.ToLower(arguments.Arg("area")), r.cache)
+	if err != nil {
+		return err
 	}
-	return nil
+	r.lastExplored = encounters.Pokemon
+	return r.emit(arguments, exploreResult{Command: "explore", Area: encounters.Name, AreaName: encounters.DisplayName, Pokemon: encounters.Pokemon})
 }
-func exploreArea(arguments string) error {
-	pokemon, err := pokeapi.GetPokemonInArea(arguments, &_cached_storage)
+
+func (r *repl) catchPokemon(arguments commandArgs) error {
+	pokemon := strings.ToLower(arguments.Arg("pokemon"))
+	_, known := r.pokedex.Get(pokemon)
+	caught, err := r.api.Catch(pokemon, r.rand, r.cache, r.pokedex)
 	if err != nil {
 		return err
 	}
-	for _, mon := range pokemon {
-		fmt.Printf("%v\n", mon)
+	// Catching a pokemon already in the pokedex leaves nothing new to save
+	if caught && !known {
+		r.unsaved.Add(1)
 	}
-	return nil
+	return r.emit(arguments, catchResult{Command: "catch", Pokemon: pokemon, Caught: caught})
+}
+
+func (r *repl) inspectPokemon(arguments commandArgs) error {
+	info, err := pokeapi.Inspect(strings.ToLower(arguments.Arg("pokemon")), r.pokedex)
+	if err != nil {
+		return err
+	}
+	details := newInspectResult(info)
+	format, err := resultFormat(arguments)
+	if err != nil {
+		return err
+	}
+	drawSprite := arguments.Bool("sprite") || lineedit.IsTerminal(terminalFd(r.out))
+	if format == "text" && drawSprite && !arguments.Bool("no-sprite") {
+		// The sprite is only decoration, so inspect goes on without it if it can't be fetched
+		if sprite, err := r.api.Sprite(info, r.cache); err == nil {
+			details.sprite = sprite
+		}
+	}
+	return r.emit(arguments, details)
 }
 
-func catchPokemon(pokemon string) error {
-	success, err := pokeapi.CatchPokemon(pokemon, &_cached_storage, _pokedex_storage)
+func (r *repl) explorePokedex(arguments commandArgs) error {
+	entries, err := pokeapi.PokedexEntries(r.pokedex)
 	if err != nil {
 		return err
 	}
-	fmt.Printf("%s\n", success)
+	pokedex := pokedexResult{Command: "pokedex", Pokemon: make([]string, 0, len(entries)), types: make(map[string][]string)}
+	for _, info := range entries {
+		pokedex.Pokemon = append(pokedex.Pokemon, info.Name)
+		pokedex.types[info.Name] = newInspectResult(info).Types
+	}
+	return r.emit(arguments, pokedex)
+}
+
+// Prints entry counts, hit rates and the compression ratio of the response cache and pokedex
+func (r *repl) cacheStats(arguments commandArgs) error {
+	printCacheStats(r.out, "Response cache", r.cache.Stats())
+	printCacheStats(r.out, "Pokedex", r.pokedex.Stats())
 	return nil
 }
 
-func inspectPokemon(pokemon string) error {
-	success, err := pokeapi.InspectPokemon(pokemon, _pokedex_storage)
+// Writes the response cache to file as a bundle
+func (r *repl) exportCache(arguments commandArgs) error {
+	file := arguments.Arg("file")
+	info, err := exportBundle(r.cache, file)
 	if err != nil {
 		return err
 	}
-	fmt.Printf("%s\n", success)
+	fmt.Fprintf(r.out, "Exported %d cached responses to %s\n", info.Entries, file)
 	return nil
 }
 
-func explorePokedex(arguments string) error {
-	success, err := pokeapi.ExplorePokedex(_pokedex_storage)
+// Writes the values of c to file as a bundle
+// The bundle is written to a temporary file first so a failed export leaves no partial file
+func exportBundle(c *pokecache.Cache, file string) (pokecache.BundleInfo, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*.tmp")
+	if err != nil {
+		return pokecache.BundleInfo{}, err
+	}
+	defer os.Remove(tmp.Name())
+	// Bundles are meant to be shared, unlike the private default of CreateTemp
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return pokecache.BundleInfo{}, err
+	}
+	info, err := c.Export(tmp)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return info, err
+	}
+	return info, os.Rename(tmp.Name(), file)
+}
+
+// Adds the responses in the bundle file to the response cache
+func (r *repl) importCache(arguments commandArgs) error {
+	return r.importBundle(arguments.Arg("file"))
+}
+
+// Adds the responses in the bundle file to the response cache
+func (r *repl) importBundle(file string) error {
+	f, err := os.Open(file)
 	if err != nil {
 		return err
 	}
-	fmt.Println(success)
+	defer f.Close()
+	info, err := r.cache.Import(f)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(r.out, "Imported %d cached responses exported at %s\n", info.Entries, info.ExportedAt.Format(time.RFC1123))
 	return nil
 }
+
+func printCacheStats(w io.Writer, title string, stats pokecache.Stats) {
+	fmt.Fprintf(w, "%s:\n", title)
+	fmt.Fprintf(w, "\tentries: %d (%d compressed) across %d shards\n", stats.Entries, stats.Compressed, stats.Shards)
+	fmt.Fprintf(w, "\tsize: %d bytes stored, %d bytes uncompressed (ratio %.2f)\n", stats.StoredBytes, stats.RawBytes, stats.CompressionRatio())
+	fmt.Fprintf(w, "\thits: %d, stale hits: %d, disk hits: %d, misses: %d\n", stats.Hits, stats.StaleHits, stats.DiskHits, stats.Misses)
+	fmt.Fprintf(w, "\tnot found: %d remembered, %d hits\n", stats.NegativeEntries, stats.NegativeHits)
+}
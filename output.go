@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"internal/pokeapi"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Formats results can be written in
+var outputFormats = []string{"text", "json", "ndjson", "yaml"}
+
+// Format used by commands not given --output
+var outputFormat = "text"
+
+// Flag selecting the format of a command's result
+// Commands producing results declare it; an empty value means outputFormat
+var outputFlag = flagSpec{name: "output", usage: "result format: " + strings.Join(outputFormats, ", ")}
+
+// Result of a command that can describe itself as text
+// The JSON encoding of each result is the documented machine-readable schema
+type result interface {
+	writeText(w io.Writer, st textStyle)
+}
+
+// Returns the format chosen by the command's --output flag, or the default
+// Returns a usageError for unknown formats
+func resultFormat(arguments commandArgs) (string, error) {
+	format := arguments.Flag("output")
+	if format == "" {
+		format = outputFormat
+	}
+	for _, f := range outputFormats {
+		if strings.EqualFold(format, f) {
+			return f, nil
+		}
+	}
+	return "", &usageError{msg: fmt.Sprintf("unknown output format %q; use one of %s", format, strings.Join(outputFormats, ", "))}
+}
+
+// Writes the result to the session's output in the format chosen for the command
+func (r *repl) emit(arguments commandArgs, res result) error {
+	format, err := resultFormat(arguments)
+	if err != nil {
+		return err
+	}
+	return writeResult(r.out, format, res, styleFor(r.out))
+}
+
+// Writes the result to w as human readable text in the given style, indented JSON,
+// a single line of JSON or a YAML document
+func writeResult(w io.Writer, format string, r result, st textStyle) error {
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	case "ndjson":
+		data, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	case "yaml":
+		return writeYAML(w, r)
+	default:
+		r.writeText(w, st)
+		return nil
+	}
+}
+
+// Result of map and mapb: one page of location areas
+type mapResult struct {
+	Command string   `json:"command"`
+	Areas   []string `json:"areas"`
+}
+
+func (r mapResult) writeText(w io.Writer, st textStyle) {
+	for _, area := range r.Areas {
+		fmt.Fprintf(w, "%v\n", area)
+	}
+}
+
+// Result of explore: the pokemon that can be encountered in an area
+type exploreResult struct {
+	Command  string   `json:"command"`
+	Area     string   `json:"area"`
+	AreaName string   `json:"area_name"`
+	Pokemon  []string `json:"pokemon"`
+}
+
+func (r exploreResult) writeText(w io.Writer, st textStyle) {
+	name := r.AreaName
+	if name == "" {
+		name = r.Area
+	}
+	fmt.Fprintln(w, st.paint("1", "Pokemon in "+name+":"))
+	writeColumns(w, st, r.Pokemon)
+}
+
+// Result of catch: whether the pokemon was caught
+type catchResult struct {
+	Command string `json:"command"`
+	Pokemon string `json:"pokemon"`
+	Caught  bool   `json:"caught"`
+}
+
+func (r catchResult) writeText(w io.Writer, st textStyle) {
+	if r.Caught {
+		fmt.Fprintf(w, "%s has been captured!\n", r.Pokemon)
+	} else {
+		fmt.Fprintf(w, "%s has escaped!\n", r.Pokemon)
+	}
+}
+
+// Result of inspect: details of a caught pokemon
+type inspectResult struct {
+	Command        string      `json:"command"`
+	Name           string      `json:"name"`
+	ID             int         `json:"id"`
+	Height         int         `json:"height"`
+	Weight         int         `json:"weight"`
+	BaseExperience int         `json:"base_experience"`
+	Types          []string    `json:"types"`
+	Stats          []statValue `json:"stats"`
+	// Drawn above the text output when set
+	sprite image.Image
+}
+
+// Base value of one of a pokemon's stats
+type statValue struct {
+	Name   string `json:"name"`
+	Base   int    `json:"base"`
+	Effort int    `json:"effort"`
+}
+
+// Builds the inspect result from the pokemon's API data
+func newInspectResult(info pokeapi.PokemonDetailedInformation) inspectResult {
+	r := inspectResult{
+		Command:        "inspect",
+		Name:           info.Name,
+		ID:             info.Id,
+		Height:         info.Height,
+		Weight:         info.Weight,
+		BaseExperience: info.Base_experience,
+		Types:          make([]string, 0, len(info.Types)),
+		Stats:          make([]statValue, 0, len(info.Stats)),
+	}
+	for _, t := range info.Types {
+		r.Types = append(r.Types, t.Type.Name)
+	}
+	for _, s := range info.Stats {
+		r.Stats = append(r.Stats, statValue{Name: s.Stat.Name, Base: s.Base_stat, Effort: s.Effort})
+	}
+	return r
+}
+
+// Shortest stat bar worth drawing, and the longest drawn however wide the terminal
+const (
+	minStatBar = 10
+	maxStatBar = 40
+)
+
+func (r inspectResult) writeText(w io.Writer, st textStyle) {
+	if r.sprite != nil {
+		writeSprite(w, r.sprite, st)
+	}
+	fmt.Fprintf(w, "%s #%d\n", st.paint("1", r.Name), r.ID)
+	table{rows: [][]string{
+		{"Types", st.typeNames(r.Types)},
+		{"Height", strconv.Itoa(r.Height)},
+		{"Weight", strconv.Itoa(r.Weight)},
+		{"Base XP", strconv.Itoa(r.BaseExperience)},
+	}}.write(w, st)
+	fmt.Fprintln(w)
+
+	stats := table{header: []string{"Stat", "Base", "EV"}, right: []bool{false, true, true}}
+	for _, s := range r.Stats {
+		stats.rows = append(stats.rows, []string{s.Name, strconv.Itoa(s.Base), strconv.Itoa(s.Effort)})
+	}
+	// The bars take whatever room the other columns leave
+	used := 0
+	for _, width := range stats.widths() {
+		used += width + 2
+	}
+	if length := min(st.width-used, maxStatBar); length >= minStatBar {
+		for i, s := range r.Stats {
+			stats.rows[i] = append(stats.rows[i], st.statBar(s.Base, length))
+		}
+	}
+	stats.write(w, st)
+}
+
+// Result of pokedex: the names of every caught pokemon
+type pokedexResult struct {
+	Command string   `json:"command"`
+	Pokemon []string `json:"pokemon"`
+	// Types of each pokemon, shown in the text table only
+	types map[string][]string
+}
+
+func (r pokedexResult) writeText(w io.Writer, st textStyle) {
+	if len(r.Pokemon) == 0 {
+		fmt.Fprintln(w, "Your pokedex is empty")
+		return
+	}
+	fmt.Fprintf(w, "%s %d\n", st.paint("1", "Your Pokemon:"), len(r.Pokemon))
+	t := table{header: []string{"Name", "Types"}}
+	for _, name := range r.Pokemon {
+		t.rows = append(t.rows, []string{name, st.typeNames(r.types[name])})
+	}
+	t.write(w, st)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testInspect = inspectResult{
+	Command:        "inspect",
+	Name:           "pikachu",
+	ID:             25,
+	Height:         4,
+	Weight:         60,
+	BaseExperience: 112,
+	Types:          []string{"electric"},
+	Stats:          []statValue{{Name: "hp", Base: 35}, {Name: "speed", Base: 90, Effort: 2}},
+}
+
+func TestWriteResultFormats(t *testing.T) {
+	cases := []struct {
+		format string
+		r      result
+		want   string
+	}{
+		{"text", testInspect, `pikachu #25
+Types    electric
+Height   4
+Weight   60
+Base XP  112
+
+Stat   Base  EV
+hp       35   0  ` + strings.Repeat("█", 5) + strings.Repeat("░", 35) + `
+speed    90   2  ` + strings.Repeat("█", 14) + strings.Repeat("░", 26) + "\n"},
+		{"text", catchResult{Command: "catch", Pokemon: "mew", Caught: false}, "mew has escaped!\n"},
+		{"text", pokedexResult{Command: "pokedex", Pokemon: []string{"mew", "charizard"}, types: map[string][]string{"charizard": {"fire", "flying"}}}, "Your Pokemon: 2\nName       Types\nmew\ncharizard  fire flying\n"},
+		{"text", pokedexResult{Command: "pokedex", Pokemon: []string{}}, "Your pokedex is empty\n"},
+		{"text", exploreResult{Command: "explore", Area: "canalave-city-area", AreaName: "Canalave City", Pokemon: []string{"tentacool", "wingull", "shellos"}}, "Pokemon in Canalave City:\ntentacool  wingull  shellos\n"},
+		{"ndjson", catchResult{Command: "catch", Pokemon: "mew", Caught: true}, `{"command":"catch","pokemon":"mew","caught":true}` + "\n"},
+		{"ndjson", mapResult{Command: "mapb", Areas: []string{"a", "b"}}, `{"command":"mapb","areas":["a","b"]}` + "\n"},
+		{"json", pokedexResult{Command: "pokedex", Pokemon: []string{}}, "{\n  \"command\": \"pokedex\",\n  \"pokemon\": []\n}\n"},
+		{"yaml", testInspect, `---
+command: inspect
+name: pikachu
+id: 25
+height: 4
+weight: 60
+base_experience: 112
+types:
+  - electric
+stats:
+  - name: hp
+    base: 35
+    effort: 0
+  - name: speed
+    base: 90
+    effort: 2
+`},
+		{"yaml", exploreResult{Command: "explore", Area: "true", AreaName: "True", Pokemon: []string{"mr-mime", "null", "a: b", ""}}, `---
+command: explore
+area: "true"
+area_name: "True"
+pokemon:
+  - mr-mime
+  - "null"
+  - "a: b"
+  - ""
+`},
+		{"yaml", pokedexResult{Command: "pokedex", Pokemon: []string{}}, "---\ncommand: pokedex\npokemon: []\n"},
+	}
+	for _, c := range cases {
+		var out bytes.Buffer
+		if err := writeResult(&out, c.format, c.r, plainStyle); err != nil {
+			t.Errorf("writeResult(%s, %T) error: %v", c.format, c.r, err)
+			continue
+		}
+		if out.String() != c.want {
+			t.Errorf("writeResult(%s, %T) =\n%s\nwant\n%s", c.format, c.r, out.String(), c.want)
+		}
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	var out bytes.Buffer
+	if err := writeResult(&out, "json", testInspect, plainStyle); err != nil {
+		t.Fatal(err)
+	}
+	var decoded inspectResult
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, testInspect) {
+		t.Errorf("decoded %+v, want %+v", decoded, testInspect)
+	}
+}
+
+func TestResultFormat(t *testing.T) {
+	defer func() { outputFormat = "text" }()
+	outputFormat = "ndjson"
+	if f, err := resultFormat(commandArgs{}); err != nil || f != "ndjson" {
+		t.Errorf("default format = %q, %v, want ndjson", f, err)
+	}
+	if f, err := resultFormat(commandArgs{flags: map[string]string{"output": "YAML"}}); err != nil || f != "yaml" {
+		t.Errorf("--output YAML = %q, %v, want yaml", f, err)
+	}
+	var usage *usageError
+	if _, err := resultFormat(commandArgs{flags: map[string]string{"output": "xml"}}); !errors.As(err, &usage) {
+		t.Errorf("--output xml error = %v, want usage error", err)
+	}
+}
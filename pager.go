@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"internal/lineedit"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"unicode/utf8"
+)
+
+// Output of one command on a terminal
+// It is written straight to the terminal while it fits on the screen, so slow commands show their progress
+// Once it no longer fits, what was shown is cleared and all of it goes to the pager instead:
+// a pager program is fed as the command writes, the built-in pager is shown when the command ends
+type pagedOutput struct {
+	r        *repl
+	terminal *os.File
+	width    int
+	height   int
+	// Everything the command wrote
+	output bytes.Buffer
+	// Set once the output is taller than the screen
+	overflowed bool
+	// Input of the pager program, and a function waiting for it to exit; nil for the built-in pager
+	pager io.WriteCloser
+	wait  func()
+}
+
+// Returns the terminal's descriptor, so styleFor sizes and colors the output for it
+func (p *pagedOutput) Fd() uintptr {
+	return p.terminal.Fd()
+}
+
+func (p *pagedOutput) Write(b []byte) (int, error) {
+	shown := p.output.Len()
+	p.output.Write(b)
+	switch {
+	case p.pager != nil:
+		// Errors mean the pager was quit early, which drops the rest of the output
+		p.pager.Write(b)
+	case p.overflowed:
+	case displayRows(p.output.String(), p.width) < p.height:
+		return p.terminal.Write(b)
+	default:
+		p.overflowed = true
+		clearRows(p.terminal, p.output.String()[:shown], p.width)
+		p.pager, p.wait = p.r.startPager(p.terminal)
+		if p.pager != nil {
+			p.pager.Write(p.output.Bytes())
+		}
+	}
+	return len(b), nil
+}
+
+// Ends the output once the command has finished, waiting for the pager or showing the built-in one
+func (p *pagedOutput) finish(editor *lineedit.Editor) {
+	switch {
+	case p.pager != nil:
+		p.pager.Close()
+		p.wait()
+	case p.overflowed:
+		editor.Page(wrapRows(p.output.String(), p.width), p.height)
+	}
+}
+
+// Sends what the next command writes to the session's output through the pager when the output is a terminal,
+// the session is interactive and the pager setting allows paging
+// Returns a function to call once the command has finished
+func (r *repl) startPaging(editor *lineedit.Editor) func() {
+	terminal, ok := r.out.(*os.File)
+	if !ok || !r.interactive || cfg.get("pager") == "off" {
+		return func() {}
+	}
+	height := lineedit.Height(int(terminal.Fd()))
+	if height == 0 {
+		return func() {}
+	}
+	p := &pagedOutput{r: r, terminal: terminal, width: styleFor(terminal).width, height: height}
+	r.out = p
+	return func() {
+		r.out = terminal
+		p.finish(editor)
+	}
+}
+
+// Starts the pager setting's program, $PAGER for "auto", writing to the terminal
+// Returns its input and a function that waits for it to exit once the input is closed
+// Returns a nil input when the built-in pager is to be used, including when the program cannot be started
+func (r *repl) startPager(terminal *os.File) (io.WriteCloser, func()) {
+	program := cfg.get("pager")
+	if program == "auto" {
+		program = os.Getenv("PAGER")
+	}
+	fields := strings.Fields(program)
+	if len(fields) == 0 || program == "builtin" {
+		return nil, nil
+	}
+	cmd := exec.Command(fields[0], fields[1:]...)
+	cmd.Stdout = terminal
+	cmd.Stderr = r.errOut
+	// Like git, let less pass colors through and quit on output that fits after all
+	if _, ok := os.LookupEnv("LESS"); !ok {
+		cmd.Env = append(os.Environ(), "LESS=FRX")
+	}
+	in, err := cmd.StdinPipe()
+	if err == nil {
+		err = cmd.Start()
+	}
+	if err != nil {
+		logger.Warn("pager could not be started, using the built-in pager", "pager", program, "err", err)
+		return nil, nil
+	}
+	// Ctrl-C is the pager's to handle while it runs
+	r.paging.Store(true)
+	return in, func() {
+		cmd.Wait()
+		r.paging.Store(false)
+	}
+}
+
+// Returns the number of terminal rows s takes up, with lines longer than width wrapping onto the next row
+// A width of zero or less leaves lines unwrapped
+func displayRows(s string, width int) int {
+	return len(wrapRows(s, width))
+}
+
+// Splits s into the rows a terminal width columns wide shows it as
+// Color codes take up no columns and are kept with the text they color
+func wrapRows(s string, width int) []string {
+	if s == "" {
+		return nil
+	}
+	var rows []string
+	for _, line := range strings.Split(strings.TrimSuffix(s, "\n"), "\n") {
+		for width > 0 && textWidth(line) > width {
+			cut, columns := 0, 0
+			for cut < len(line) {
+				if strings.HasPrefix(line[cut:], "\x1b[") {
+					if loc := ansiEscape.FindStringIndex(line[cut:]); loc != nil && loc[0] == 0 {
+						cut += loc[1]
+						continue
+					}
+				}
+				if columns == width {
+					break
+				}
+				_, size := utf8.DecodeRuneInString(line[cut:])
+				cut += size
+				columns++
+			}
+			rows = append(rows, line[:cut])
+			line = line[cut:]
+		}
+		rows = append(rows, line)
+	}
+	return rows
+}
+
+// Moves the cursor back to the start of output already written to the terminal and clears it from the screen
+func clearRows(terminal io.Writer, output string, width int) {
+	up := displayRows(output, width)
+	if output != "" && !strings.HasSuffix(output, "\n") {
+		// The cursor is still on the last row
+		up--
+	}
+	fmt.Fprint(terminal, "\r")
+	if up > 0 {
+		fmt.Fprintf(terminal, "\x1b[%dA", up)
+	}
+	fmt.Fprint(terminal, "\x1b[J")
+}
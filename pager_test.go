@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"internal/lineedit"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPageThroughProgram(t *testing.T) {
+	useConfigFile(t, "")
+	out, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	r := newREPL(strings.NewReader(""), out, os.Stderr, nil, nil)
+
+	cfg.set("pager", "cat -n", "flag")
+	in, wait := r.startPager(out)
+	if in == nil {
+		t.Fatal("startPager did not start cat")
+	}
+	io.WriteString(in, "bulbasaur\nivysaur\n")
+	io.WriteString(in, "venusaur\n")
+	in.Close()
+	wait()
+	data, err := os.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "     1\tbulbasaur\n     2\tivysaur\n     3\tvenusaur\n"; string(data) != want {
+		t.Errorf("paged through cat -n:\n%q\nwant\n%q", data, want)
+	}
+	if r.paging.Load() {
+		t.Errorf("paging still set after the pager exited")
+	}
+
+	// Set directly, as the setting's check would refuse it
+	cfg.values["pager"] = "no-such-pager-program"
+	if in, _ := r.startPager(out); in != nil {
+		t.Errorf("startPager of a missing program gave an input, want nil for the built-in pager")
+	}
+}
+
+func TestPagedOutputOverflow(t *testing.T) {
+	useConfigFile(t, "")
+	cfg.set("pager", "builtin", "flag")
+	terminal, err := os.Create(filepath.Join(t.TempDir(), "terminal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer terminal.Close()
+	r := newREPL(strings.NewReader(""), terminal, io.Discard, nil, nil)
+	p := &pagedOutput{r: r, terminal: terminal, width: 10, height: 4}
+
+	// Two rows fit, so they are shown as they are written
+	io.WriteString(p, "pidgey\nrattata\n")
+	if data, _ := os.ReadFile(terminal.Name()); string(data) != "pidgey\nrattata\n" {
+		t.Fatalf("terminal before overflow = %q, want the output so far", data)
+	}
+	// A line wrapping onto three rows does not fit, so what was shown is cleared for the pager
+	io.WriteString(p, strings.Repeat("x", 25)+"\n")
+	if data, _ := os.ReadFile(terminal.Name()); string(data) != "pidgey\nrattata\n\r\x1b[2A\x1b[J" {
+		t.Fatalf("terminal after overflow = %q, want the output cleared", data)
+	}
+
+	var paged bytes.Buffer
+	p.finish(lineedit.New(strings.NewReader(""), &paged))
+	if want := "pidgey\nrattata\nxxxxxxxxxx\nxxxxxxxxxx\nxxxxx\n"; paged.String() != want {
+		t.Errorf("built-in pager showed %q, want %q", paged.String(), want)
+	}
+}
+
+func TestWrapRows(t *testing.T) {
+	cases := []struct {
+		s     string
+		width int
+		want  []string
+	}{
+		{"", 10, nil},
+		{"abc\n", 10, []string{"abc"}},
+		{"abcdef", 3, []string{"abc", "def"}},
+		{"abcdefg\nhi\n", 3, []string{"abc", "def", "g", "hi"}},
+		{"\x1b[1mabc\x1b[0mdef", 3, []string{"\x1b[1mabc\x1b[0m", "def"}},
+		{"ééééé", 2, []string{"éé", "éé", "é"}},
+		{"abcdef", 0, []string{"abcdef"}},
+	}
+	for _, c := range cases {
+		if got := wrapRows(c.s, c.width); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("wrapRows(%q, %d) = %q, want %q", c.s, c.width, got, c.want)
+		}
+	}
+}
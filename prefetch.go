@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"internal/pokeapi"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Number of concurrent requests a prefetch makes unless told otherwise
+const defaultPrefetchWorkers = 8
+
+func init() {
+	registry.register(cliCommand{
+		name:        "prefetch",
+		category:    "Cache",
+		description: "Downloads a generation or region into the cache",
+		long: "Fetches every species, pokemon, location area and encounter of a generation or region so they can be used offline.\n" +
+			"Interrupted prefetches resume where they stopped when run again.",
+		args:     []argSpec{{name: "kind"}, {name: "name"}},
+		flags:    []flagSpec{{name: "workers", usage: "number of concurrent requests", defValue: fmt.Sprint(defaultPrefetchWorkers)}},
+		examples: []string{"prefetch generation 1", "prefetch --workers 4 region kanto"},
+		callback: (*repl).prefetchCommand,
+	})
+}
+
+// Downloads a generation or region into the response cache from the REPL
+// Takes in the kind ("generation" or "region"), its name and an optional --workers count
+func (r *repl) prefetchCommand(arguments commandArgs) error {
+	workers, err := strconv.Atoi(arguments.Flag("workers"))
+	if err != nil || workers < 1 {
+		return fmt.Errorf("--workers must be a positive number\n")
+	}
+	return r.prefetch(context.Background(), strings.ToLower(arguments.Arg("kind")), strings.ToLower(arguments.Arg("name")), workers)
+}
+
+// Runs "pokedexcli prefetch [-workers n] generation|region <name>"
+// Interrupting with Ctrl-C stops the prefetch and keeps what was fetched so a rerun resumes
+// Returns the process exit code
+func runPrefetch(r *repl, args []string) int {
+	flags := flag.NewFlagSet("prefetch", flag.ContinueOnError)
+	workers := flags.Int("workers", defaultPrefetchWorkers, "number of concurrent requests")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: pokedexcli prefetch [-workers n] generation <id> | region <name>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 2
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := r.prefetch(ctx, flags.Arg(0), flags.Arg(1), *workers); err != nil {
+		fmt.Fprint(r.errOut, err)
+		return 1
+	}
+	return 0
+}
+
+// Runs a prefetch, printing progress on one line and a summary with any failures
+// Returns an error if any resource failed
+func (r *repl) prefetch(ctx context.Context, kind string, name string, workers int) error {
+	summary, err := r.api.Prefetch(ctx, kind, name, r.cache, workers, func(p pokeapi.PrefetchProgress) {
+		fmt.Fprintf(r.out, "\rprefetch: %d/%d done, %d cached, %d failed", p.Done, p.Total, p.Cached, p.Failed)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(r.out)
+	fmt.Fprintf(r.out, "Prefetched %d resources in %s (%d already cached, %d failed)\n",
+		summary.Done, summary.Duration.Round(time.Millisecond), summary.Cached, summary.Failed)
+	for _, failure := range summary.Failures {
+		fmt.Fprintf(r.out, "\t-%s: %s\n", failure.URL, failure.Err)
+	}
+	if summary.Interrupted {
+		fmt.Fprintln(r.out, "Prefetch interrupted; run it again to resume")
+	}
+	if summary.Failed > 0 {
+		return fmt.Errorf("%d resources failed to prefetch\n", summary.Failed)
+	}
+	return nil
+}
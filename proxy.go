@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"internal/pokeapi"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Runs "pokedexcli proxy [--listen addr] [--rate n] [--burst n]"
+// Serves the PokeAPI v2 paths from the response cache until interrupted, then flushes the cache
+// Returns the process exit code
+func runProxy(r *repl, args []string) int {
+	flags := flag.NewFlagSet("proxy", flag.ContinueOnError)
+	listen := flags.String("listen", ":8080", "address to serve on")
+	rate := flags.Float64("rate", 10, "maximum upstream requests per second; 0 for no limit")
+	burst := flags.Int("burst", 20, "upstream requests allowed at once before the rate applies")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: pokedexcli proxy [--listen addr] [--rate n] [--burst n]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return 2
+	}
+	pokeapi.SetRateLimit(*rate, *burst)
+
+	server := &http.Server{
+		Addr:              *listen,
+		Handler:           &pokeapi.Proxy{Cache: r.cache, Client: r.api},
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.ListenAndServe()
+	}()
+	logger.Info("proxy serving PokeAPI v2", "listen", *listen, "rate", *rate, "burst", *burst)
+
+	select {
+	case err := <-errs:
+		logger.Error("proxy stopped", "listen", *listen, "err", err)
+		return 1
+	case <-ctx.Done():
+	}
+	logger.Info("proxy shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error("proxy shutdown failed", "err", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"internal/lineedit"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Width assumed when the output is not a terminal and $COLUMNS is unset
+const defaultWidth = 80
+
+// Highest base stat a pokemon can have, the length of a full stat bar
+const maxBaseStat = 255
+
+// How text results are drawn
+type textStyle struct {
+	// Whether ANSI colors are written
+	color bool
+	// Columns available for a line
+	width int
+}
+
+// Style for text written somewhere other than a terminal
+var plainStyle = textStyle{width: defaultWidth}
+
+// Returns the style for text written to w, sized to the terminal w writes to if it has a descriptor
+// The color setting decides color: "auto" colors only a terminal and only when NO_COLOR is unset
+func styleFor(w io.Writer) textStyle {
+	st := plainStyle
+	fd := terminalFd(w)
+	if width := lineedit.Width(fd); width > 0 {
+		st.width = width
+	} else if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		st.width = columns
+	}
+	switch cfg.get("color") {
+	case "always":
+		st.color = true
+	case "auto":
+		st.color = lineedit.IsTerminal(fd) && os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb"
+	}
+	return st
+}
+
+// Returns the descriptor of the file w writes to, or -1 if it is not a file
+// Output held back for the pager reports the terminal it will be shown on
+func terminalFd(w io.Writer) int {
+	if f, ok := w.(interface{ Fd() uintptr }); ok {
+		return int(f.Fd())
+	}
+	return -1
+}
+
+// Wraps s in the ANSI graphics code when color is on
+func (st textStyle) paint(code string, s string) string {
+	if !st.color || code == "" {
+		return s
+	}
+	return "\x1b[" + code + "m" + s + "\x1b[0m"
+}
+
+// xterm-256 colors of each pokemon type
+var typeColors = map[string]int{
+	"normal":   145,
+	"fire":     208,
+	"water":    69,
+	"electric": 220,
+	"grass":    77,
+	"ice":      117,
+	"fighting": 160,
+	"poison":   128,
+	"ground":   178,
+	"flying":   147,
+	"psychic":  204,
+	"bug":      106,
+	"rock":     137,
+	"ghost":    97,
+	"dragon":   99,
+	"dark":     94,
+	"steel":    110,
+	"fairy":    218,
+}
+
+// Returns the type name in its color
+func (st textStyle) typeName(name string) string {
+	color, ok := typeColors[name]
+	if !ok {
+		return name
+	}
+	return st.paint("38;5;"+strconv.Itoa(color), name)
+}
+
+// Returns the type names in their colors, separated by spaces
+func (st textStyle) typeNames(names []string) string {
+	painted := make([]string, len(names))
+	for i, name := range names {
+		painted[i] = st.typeName(name)
+	}
+	return strings.Join(painted, " ")
+}
+
+// Returns a bar of the given length filled in proportion to a base stat
+// The bar goes from red for weak stats to cyan for the strongest
+func (st textStyle) statBar(base int, length int) string {
+	filled := (base*length + maxBaseStat/2) / maxBaseStat
+	filled = min(max(filled, 0), length)
+	code := "36"
+	switch {
+	case base < 60:
+		code = "31"
+	case base < 90:
+		code = "33"
+	case base < 120:
+		code = "32"
+	}
+	return st.paint(code, strings.Repeat("█", filled)) + strings.Repeat("░", length-filled)
+}
+
+// ANSI escape sequences, which take no room on screen
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// Returns the number of columns s takes on screen
+func textWidth(s string) int {
+	return utf8.RuneCountInString(ansiEscape.ReplaceAllString(s, ""))
+}
+
+// Rows of cells written with every column padded to its widest cell
+type table struct {
+	header []string
+	rows   [][]string
+	// Columns aligned to the right, such as numbers
+	right []bool
+}
+
+// Returns the width of each column
+func (t table) widths() []int {
+	var widths []int
+	for _, row := range append([][]string{t.header}, t.rows...) {
+		for i, cell := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], textWidth(cell))
+		}
+	}
+	return widths
+}
+
+// Writes the header in bold followed by the rows, with two spaces between columns
+func (t table) write(w io.Writer, st textStyle) {
+	widths := t.widths()
+	writeRow := func(row []string, code string) {
+		var line strings.Builder
+		for i, cell := range row {
+			pad := strings.Repeat(" ", widths[i]-textWidth(cell))
+			if i > 0 {
+				line.WriteString("  ")
+			}
+			if i < len(t.right) && t.right[i] {
+				line.WriteString(pad + st.paint(code, cell))
+			} else {
+				line.WriteString(st.paint(code, cell) + pad)
+			}
+		}
+		fmt.Fprintln(w, strings.TrimRight(line.String(), " "))
+	}
+	if len(t.header) > 0 {
+		writeRow(t.header, "1")
+	}
+	for _, row := range t.rows {
+		writeRow(row, "")
+	}
+}
+
+// Writes names in as many columns as fit the width, filled top to bottom
+func writeColumns(w io.Writer, st textStyle, names []string) {
+	if len(names) == 0 {
+		return
+	}
+	cell := 0
+	for _, name := range names {
+		cell = max(cell, textWidth(name))
+	}
+	cols := max(1, (st.width+2)/(cell+2))
+	rows := (len(names) + cols - 1) / cols
+	t := table{rows: make([][]string, rows)}
+	for i, name := range names {
+		t.rows[i%rows] = append(t.rows[i%rows], name)
+	}
+	t.write(w, st)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestStyleFor(t *testing.T) {
+	defer cfg.set("color", "auto", "default")
+	f, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	t.Setenv("COLUMNS", "120")
+	t.Setenv("NO_COLOR", "")
+
+	cases := []struct {
+		color   string
+		noColor string
+		want    bool
+	}{
+		{"auto", "", false},
+		{"always", "", true},
+		{"always", "1", true},
+		{"never", "", false},
+	}
+	for _, c := range cases {
+		cfg.set("color", c.color, "flag")
+		t.Setenv("NO_COLOR", c.noColor)
+		st := styleFor(f)
+		if st.color != c.want || st.width != 120 {
+			t.Errorf("color=%s NO_COLOR=%q: style %+v, want color %v and width 120", c.color, c.noColor, st, c.want)
+		}
+	}
+}
+
+func TestTableAlignsColoredCells(t *testing.T) {
+	st := textStyle{color: true, width: 80}
+	var out bytes.Buffer
+	table{
+		header: []string{"Name", "Types"},
+		rows:   [][]string{{"mew", st.typeNames([]string{"psychic"})}, {"charizard", st.typeNames([]string{"fire", "flying"})}},
+	}.write(&out, st)
+	want := "\x1b[1mName\x1b[0m       \x1b[1mTypes\x1b[0m\n" +
+		"mew        \x1b[38;5;204mpsychic\x1b[0m\n" +
+		"charizard  \x1b[38;5;208mfire\x1b[0m \x1b[38;5;147mflying\x1b[0m\n"
+	if out.String() != want {
+		t.Errorf("table =\n%q\nwant\n%q", out.String(), want)
+	}
+}
+
+func TestWriteColumnsFitsWidth(t *testing.T) {
+	names := []string{"zubat", "geodude", "onix", "machop", "tentacool"}
+	cases := []struct {
+		width int
+		want  string
+	}{
+		{80, "zubat  geodude  onix  machop  tentacool\n"},
+		{30, "zubat    machop\ngeodude  tentacool\nonix\n"},
+		{5, "zubat\ngeodude\nonix\nmachop\ntentacool\n"},
+	}
+	for _, c := range cases {
+		var out bytes.Buffer
+		writeColumns(&out, textStyle{width: c.width}, names)
+		if out.String() != c.want {
+			t.Errorf("width %d:\n%s\nwant\n%s", c.width, out.String(), c.want)
+		}
+	}
+}
+
+func TestInspectStatBarsFollowWidth(t *testing.T) {
+	for _, c := range []struct {
+		width int
+		bar   int
+	}{{200, maxStatBar}, {40, 40 - 17}, {20, 0}} {
+		var out bytes.Buffer
+		testInspect.writeText(&out, textStyle{width: c.width})
+		last := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+		row := last[len(last)-1]
+		if got := strings.Count(row, "█") + strings.Count(row, "░"); got != c.bar {
+			t.Errorf("width %d: bar of %d cells in %q, want %d", c.width, got, row, c.bar)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"internal/lineedit"
+	"internal/pokeapi"
+	"internal/pokecache"
+	"io"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// A session of commands read from in, with results written to out and notices to errOut
+// Commands reach the API, the caches and the catch rolls only through the session,
+// so a test can run whole sessions against a fake API
+type repl struct {
+	in     io.Reader
+	out    io.Writer
+	errOut io.Writer
+
+	api     *pokeapi.Client
+	cache   *pokecache.Cache
+	pokedex *pokecache.Cache
+	// Source of catch rolls; the one the seed setting controls when nil
+	rand *rand.Rand
+	// File the pokedex is saved to; "" keeps it for this session only
+	pokedexFile string
+	// Number of pokemon caught since the pokedex was last saved
+	unsaved atomic.Int64
+
+	// Set while reading commands typed at a terminal
+	interactive bool
+	// Number of scripts currently running
+	scriptDepth int
+	// Names shown by the last map or mapb, offered when completing explore
+	lastMapPage []string
+	// Pokemon shown by the last explore, offered when completing catch
+	lastExplored []string
+	// Set while an external pager runs, which handles Ctrl-C itself
+	paging atomic.Bool
+
+	shutdownOnce sync.Once
+	shutdownErr  error
+}
+
+// Returns a session reading commands from in and using the response cache and pokedex given
+// Requests go through a client of its own, so its place in the map starts at the beginning
+func newREPL(in io.Reader, out io.Writer, errOut io.Writer, cache *pokecache.Cache, pokedex *pokecache.Cache) *repl {
+	return &repl{
+		in:      in,
+		out:     out,
+		errOut:  errOut,
+		api:     &pokeapi.Client{},
+		cache:   cache,
+		pokedex: pokedex,
+	}
+}
+
+// Returned by the exit command to end the session
+var errExit = errors.New("exit")
+
+// Prompts for commands and runs them until exit or the end of the input
+// Errors are printed and the session goes on
+// Before returning, asks whether to save the pokedex if pokemon were caught since it was last saved
+// At a terminal, lines are edited with history and completion and long output is paged
+// Returns an error only if the input cannot be read
+func (r *repl) Run() error {
+	editor := lineedit.New(r.in, r.out)
+	r.interactive = editor.Interactive()
+	if r.interactive {
+		editor.Completer = r.completeLine
+		editor.History = loadHistory()
+	}
+	for {
+		line, err := editor.ReadLine("pokedex > ")
+		if err == lineedit.ErrInterrupted {
+			continue
+		}
+		if err == io.EOF {
+			// With no more input, the answer is read as the default of saving
+			r.confirmExit(editor)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		editor.History.Add(strings.TrimSpace(line))
+		showOutput := r.startPaging(editor)
+		err = r.runLine(line)
+		if err != nil && !errors.Is(err, errExit) {
+			fmt.Fprintf(r.out, "Encountered error: %s", errorText(err))
+		}
+		showOutput()
+		if errors.Is(err, errExit) && r.confirmExit(editor) {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"internal/pokeapi"
+	"internal/pokecache"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden session transcripts")
+
+// Answers requests from the responses in testdata/api.json, keyed by their path under the base URL
+// Anything else is not found
+type fakeAPI map[string]json.RawMessage
+
+func (f fakeAPI) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, ok := f[strings.TrimPrefix(req.URL.String(), pokeapi.BaseURL)]
+	status := http.StatusOK
+	if !ok {
+		status, body = http.StatusNotFound, json.RawMessage("Not Found")
+	}
+	return &http.Response{StatusCode: status, Body: io.NopCloser(bytes.NewReader(body)), Request: req}, nil
+}
+
+// Returns a session with the default settings reading in and writing both results and notices to out
+// Requests are answered by the fake API, the caches start empty and catch rolls repeat from run to run
+// The pokedex is saved to a file in a temporary directory
+func newTestREPL(t *testing.T, in io.Reader, out io.Writer) *repl {
+	t.Helper()
+	useConfigFile(t, "")
+	t.Setenv("COLUMNS", "")
+	data, err := os.ReadFile(filepath.Join("testdata", "api.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var api fakeAPI
+	if err := json.Unmarshal(data, &api); err != nil {
+		t.Fatal(err)
+	}
+	cache := pokecache.NewCache(time.Minute, pokecache.WithNegativeTTL(time.Minute))
+	t.Cleanup(func() { cache.Close() })
+	r := newREPL(in, out, out, cache, pokecache.NewCache(0))
+	r.api = &pokeapi.Client{HTTP: &http.Client{Transport: api}}
+	r.rand = rand.New(rand.NewSource(1))
+	r.pokedexFile = filepath.Join(t.TempDir(), "pokedex.bundle")
+	return r
+}
+
+// Gives the lines of input one per read, echoing each to out the way a terminal shows what is typed
+type echoReader struct {
+	lines *bufio.Reader
+	out   io.Writer
+}
+
+func (e *echoReader) Read(p []byte) (int, error) {
+	line, err := e.lines.ReadString('\n')
+	n := copy(p, line)
+	e.out.Write(p[:n])
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// Runs each session in testdata/sessions and compares its transcript with the .golden file beside it
+// Run with -update to rewrite the golden files
+func TestSessions(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "sessions", "*.txt"))
+	if err != nil || len(inputs) == 0 {
+		t.Fatalf("no sessions found: %v", err)
+	}
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".txt")
+		t.Run(name, func(t *testing.T) {
+			f, err := os.Open(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			var transcript bytes.Buffer
+			r := newTestREPL(t, &echoReader{bufio.NewReader(f), &transcript}, &transcript)
+			if err := r.Run(); err != nil {
+				t.Fatalf("Run = %v", err)
+			}
+
+			golden := strings.TrimSuffix(input, ".txt") + ".golden"
+			if *update {
+				if err := os.WriteFile(golden, transcript.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if transcript.String() != string(want) {
+				t.Errorf("transcript differs from %s:\n%s", golden, transcript.String())
+			}
+		})
+	}
+}
+
+func TestRunReturnsOnExit(t *testing.T) {
+	var out bytes.Buffer
+	r := newTestREPL(t, strings.NewReader("help exit\nquit\nmap\n"), &out)
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run = %v", err)
+	}
+	if strings.Contains(out.String(), "canalave-city-area") {
+		t.Errorf("command after quit ran:\n%s", out.String())
+	}
+	if code := exitCode(r.errOut, r.runScript("exit.txt", strings.NewReader("exit\nmap\n"))); code != exitOK {
+		t.Errorf("script stopped by exit gave exit code %d, want %d", code, exitOK)
+	}
+	if strings.Contains(out.String(), "canalave-city-area") {
+		t.Errorf("script went on after exit:\n%s", out.String())
+	}
+}
+
+func TestShutdownSavesThePokedex(t *testing.T) {
+	var out bytes.Buffer
+	r := newTestREPL(t, strings.NewReader(""), &out)
+	if err := r.runLine("explore canalave-city-area"); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"tentacool", "staryu", "wingull"} {
+		if err := r.runLine("catch " + name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if r.unsaved.Load() == 0 {
+		t.Fatalf("nothing caught to save:\n%s", out.String())
+	}
+	if err := r.shutdown(); err != nil {
+		t.Fatalf("shutdown = %v", err)
+	}
+	if n := r.unsaved.Load(); n != 0 {
+		t.Errorf("%d pokemon unsaved after shutdown", n)
+	}
+
+	pokedex := pokecache.NewCache(0)
+	defer pokedex.Close()
+	if err := loadPokedex(pokedex, r.pokedexFile); err != nil {
+		t.Fatalf("loadPokedex = %v", err)
+	}
+	if pokedex.Len() != r.pokedex.Len() {
+		t.Errorf("loaded %d pokemon, saved %d", pokedex.Len(), r.pokedex.Len())
+	}
+	if err := loadPokedex(pokedex, filepath.Join(t.TempDir(), "missing.bundle")); err != nil {
+		t.Errorf("loadPokedex of a missing file = %v, want nil", err)
+	}
+}
+
+func TestCatchCountsNewPokemonOnly(t *testing.T) {
+	r := newTestREPL(t, strings.NewReader(""), io.Discard)
+	for i := 0; i < 20; i++ {
+		if err := r.runLine("catch staryu"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, found := r.pokedex.Get("staryu"); !found {
+		t.Fatal("staryu never caught")
+	}
+	if n := r.unsaved.Load(); n != 1 {
+		t.Errorf("%d pokemon unsaved after catching staryu again, want 1", n)
+	}
+}
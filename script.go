@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Exit codes for commands run from the shell
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// Deepest nesting of scripts running scripts, to stop a script that runs itself
+const maxScriptDepth = 8
+
+func init() {
+	registry.register(cliCommand{
+		name:        "run",
+		category:    "Scripting",
+		description: "Runs the commands in a file, one per line",
+		long: "Blank lines and lines starting with # are skipped. The script stops at the first command that fails.\n" +
+			"A file of \"-\" reads the commands from stdin.",
+		args:     []argSpec{{name: "file"}},
+		examples: []string{"run kanto-tour.txt"},
+		callback: (*repl).scriptCommand,
+	})
+}
+
+// Runs the commands in the named file, or the session's input for "-"
+func (r *repl) scriptCommand(arguments commandArgs) error {
+	file := arguments.Arg("file")
+	if file == "-" {
+		return r.runScript("stdin", r.in)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return r.runScript(file, f)
+}
+
+// Runs each line read from in as a command, stopping at the first that fails or exits
+// The error is prefixed with name and the line number
+func (r *repl) runScript(name string, in io.Reader) error {
+	if r.scriptDepth >= maxScriptDepth {
+		return fmt.Errorf("%s: scripts nested more than %d deep\n", name, maxScriptDepth)
+	}
+	r.scriptDepth++
+	defer func() { r.scriptDepth-- }()
+
+	scanner := bufio.NewScanner(in)
+	for n := 1; scanner.Scan(); n++ {
+		if err := r.runLine(scanner.Text()); errors.Is(err, errExit) {
+			return err
+		} else if err != nil {
+			return fmt.Errorf("%s:%d: %w", name, n, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%s: %w\n", name, err)
+	}
+	return nil
+}
+
+// Runs "pokedexcli <subcommand> ..." or "pokedexcli <command> [args]"
+// Settings flags such as --output have already been taken from args by loadConfig
+// Errors are printed to the session's errOut
+// Returns the process exit code
+func (r *repl) runArgs(args []string) int {
+	if run, ok := subcommands[args[0]]; ok {
+		return run(r, args[1:])
+	}
+	if args[0] == "-h" || args[0] == "--help" {
+		args = []string{"help"}
+	}
+	return exitCode(r.errOut, r.runWords(args))
+}
+
+// Prints any error from running commands to errOut and returns the exit code for it
+// Usage errors exit with 2 and other failures with 1; exit is a success
+func exitCode(errOut io.Writer, err error) int {
+	if errors.Is(err, errExit) {
+		return exitOK
+	}
+	if err != nil {
+		fmt.Fprintf(errOut, "pokedexcli: %s", errorText(err))
+	}
+	var usage *usageError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &usage):
+		return exitUsage
+	default:
+		return exitError
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRunScript(t *testing.T) {
+	r := newREPL(strings.NewReader(""), io.Discard, io.Discard, nil, nil)
+	err := r.runScript("tour.txt", strings.NewReader("# comment\n\nhelp exit\n  # indented comment\nfly away\nhelp\n"))
+	if err == nil || !strings.HasPrefix(err.Error(), `tour.txt:5: unknown command "fly"`) {
+		t.Errorf("runScript error = %v, want the failing line reported", err)
+	}
+	if code := exitCode(io.Discard, err); code != exitUsage {
+		t.Errorf("exit code %d, want %d", code, exitUsage)
+	}
+	if err := r.runScript("ok.txt", strings.NewReader("help\nhelp catch\n")); err != nil {
+		t.Errorf("runScript = %v, want nil", err)
+	}
+}
+
+func TestRunScriptDepth(t *testing.T) {
+	r := newREPL(strings.NewReader(""), io.Discard, io.Discard, nil, nil)
+	r.scriptDepth = maxScriptDepth
+	if err := r.runScript("deep.txt", strings.NewReader("help\n")); err == nil {
+		t.Error("runScript past the nesting limit succeeded")
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	cases := []struct {
+		err  error
+		want int
+	}{
+		{nil, exitOK},
+		{errors.New("network down"), exitError},
+		{&usageError{msg: "missing <pokemon>"}, exitUsage},
+		{fmt.Errorf("script.txt:3: %w", &usageError{msg: "bad"}), exitUsage},
+	}
+	for _, c := range cases {
+		var errOut strings.Builder
+		if got := exitCode(&errOut, c.err); got != c.want {
+			t.Errorf("exitCode(%v) = %d, want %d", c.err, got, c.want)
+		}
+		if (c.err != nil) != strings.HasPrefix(errOut.String(), "pokedexcli: ") {
+			t.Errorf("exitCode(%v) wrote %q to errOut", c.err, errOut.String())
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"internal/lineedit"
+	"internal/pokecache"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+func init() {
+	registry.register(cliCommand{
+		name:        "save",
+		category:    "Pokemon",
+		description: "Saves your pokedex for the next session",
+		long: "The pokedex is also saved when the session ends, after asking if pokemon were caught since the last save.\n" +
+			"It is kept in the pokedex_file setting's file; \"off\" keeps caught pokemon for this session only.",
+		callback: (*repl).saveCommand,
+	})
+}
+
+// Saves the pokedex to its file
+func (r *repl) saveCommand(arguments commandArgs) error {
+	if r.pokedexFile == "" {
+		return fmt.Errorf("the pokedex is kept for this session only; set pokedex_file to save it\n")
+	}
+	if err := r.savePokedex(); err != nil {
+		return err
+	}
+	fmt.Fprintf(r.out, "Saved %d pokemon\n", r.pokedex.Len())
+	return nil
+}
+
+// Adds the pokemon saved in file to the pokedex
+// A missing file is an empty pokedex
+func loadPokedex(pokedex *pokecache.Cache, file string) error {
+	f, err := os.Open(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = pokedex.Import(f)
+	return err
+}
+
+// Writes the pokedex to its file, creating the directory if needed
+// Does nothing when the pokedex is kept for this session only
+func (r *repl) savePokedex() error {
+	if r.pokedexFile == "" {
+		return nil
+	}
+	unsaved := r.unsaved.Load()
+	if err := os.MkdirAll(filepath.Dir(r.pokedexFile), 0755); err != nil {
+		return err
+	}
+	if _, err := exportBundle(r.pokedex, r.pokedexFile); err != nil {
+		return err
+	}
+	r.unsaved.Add(-unsaved)
+	return nil
+}
+
+// Asks whether to save the pokedex before the session ends, if pokemon were caught since it was last saved
+// No answer saves it; "n" leaves it as last saved, and "c" stays in the session
+// Returns false to stay in the session, including when saving fails
+func (r *repl) confirmExit(editor *lineedit.Editor) bool {
+	for r.pokedexFile != "" && r.unsaved.Load() > 0 {
+		answer, err := editor.ReadLine(fmt.Sprintf("Save %d newly caught pokemon before exiting? [Y/n/c] ", r.unsaved.Load()))
+		switch {
+		case err == lineedit.ErrInterrupted:
+			answer = "c"
+		case err == io.EOF:
+			fmt.Fprintln(r.out)
+			answer = "y"
+		case err != nil:
+			return true
+		}
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "", "y", "yes":
+			if err := r.savePokedex(); err != nil {
+				fmt.Fprintf(r.out, "Encountered error: %s", errorText(err))
+				return false
+			}
+			return true
+		case "n", "no":
+			r.unsaved.Store(0)
+			return true
+		case "c", "cancel":
+			return false
+		}
+	}
+	return true
+}
+
+// Ends the session: saves the pokedex if it has unsaved changes, then stops the cache reapers and flushes the caches
+// Every step is attempted and the errors met are returned together
+// Later calls wait for the first to finish and return its result
+func (r *repl) shutdown() error {
+	r.shutdownOnce.Do(func() {
+		var errs []error
+		if r.unsaved.Load() > 0 {
+			errs = append(errs, r.savePokedex())
+		}
+		errs = append(errs, r.cache.Close(), r.pokedex.Close())
+		r.shutdownErr = errors.Join(errs...)
+	})
+	return r.shutdownErr
+}
+
+// Shuts the session down and exits when SIGINT or SIGTERM arrives, with 128 plus the signal's number as the exit code
+// The terminal is put back first, in case the signal came while a line was edited in raw mode
+// SIGINT is left to the pager while one runs
+func (r *repl) handleSignals() {
+	restore, err := lineedit.SaveState(int(os.Stdin.Fd()))
+	if err != nil {
+		restore = func() {}
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		for sig := range signals {
+			if sig == os.Interrupt && r.paging.Load() {
+				continue
+			}
+			restore()
+			logger.Info("shutting down", "signal", sig)
+			if err := r.shutdown(); err != nil {
+				fmt.Fprintf(r.errOut, "pokedexcli: %s", errorText(err))
+			}
+			code := exitError
+			if s, ok := sig.(syscall.Signal); ok {
+				code = 128 + int(s)
+			}
+			os.Exit(code)
+		}
+	}()
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"strings"
+)
+
+// Shades of the ASCII fallback from darkest to lightest
+const asciiShades = "@%#*+=-:."
+
+// Returns the smallest rectangle holding every visible pixel of img
+func visibleBounds(img image.Image) image.Rectangle {
+	var bounds image.Rectangle
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if _, ok := pixel(img, x, y); ok {
+				bounds = bounds.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return bounds
+}
+
+// Returns the color of the pixel at x, y and whether it is visible
+// Pixels outside the image and mostly transparent ones are not visible
+func pixel(img image.Image, x int, y int) (color.NRGBA, bool) {
+	if !(image.Point{x, y}).In(img.Bounds()) {
+		return color.NRGBA{}, false
+	}
+	c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+	return c, c.A >= 128
+}
+
+// Writes the visible part of img, each character covering two pixels stacked vertically
+// In color the pixels are drawn as truecolor half blocks, otherwise as grayscale ASCII
+// Sprites wider than the style's width are scaled down to fit
+func writeSprite(w io.Writer, img image.Image, st textStyle) {
+	bounds := visibleBounds(img)
+	if bounds.Empty() {
+		return
+	}
+	step := max(1, (bounds.Dx()+st.width-1)/max(st.width, 1))
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += 2 * step {
+		var line strings.Builder
+		for x := bounds.Min.X; x < bounds.Max.X; x += step {
+			top, topVisible := pixel(img, x, y)
+			bottom, bottomVisible := pixel(img, x, y+step)
+			if st.color {
+				line.WriteString(halfBlock(top, topVisible, bottom, bottomVisible))
+			} else {
+				line.WriteByte(asciiShade(top, topVisible, bottom, bottomVisible))
+			}
+		}
+		fmt.Fprintln(w, strings.TrimRight(line.String(), " "))
+	}
+}
+
+// Returns a cell showing the top pixel in the upper half and the bottom one in the lower half
+func halfBlock(top color.NRGBA, topVisible bool, bottom color.NRGBA, bottomVisible bool) string {
+	switch {
+	case topVisible && bottomVisible:
+		return fmt.Sprintf("\x1b[38;2;%d;%d;%d;48;2;%d;%d;%dm▀\x1b[0m", top.R, top.G, top.B, bottom.R, bottom.G, bottom.B)
+	case topVisible:
+		return fmt.Sprintf("\x1b[38;2;%d;%d;%dm▀\x1b[0m", top.R, top.G, top.B)
+	case bottomVisible:
+		return fmt.Sprintf("\x1b[38;2;%d;%d;%dm▄\x1b[0m", bottom.R, bottom.G, bottom.B)
+	default:
+		return " "
+	}
+}
+
+// Returns the ASCII shade of the mean brightness of the visible pixels, or a space if neither is
+func asciiShade(top color.NRGBA, topVisible bool, bottom color.NRGBA, bottomVisible bool) byte {
+	var sum float64
+	count := 0
+	if topVisible {
+		sum += brightness(top)
+		count++
+	}
+	if bottomVisible {
+		sum += brightness(bottom)
+		count++
+	}
+	if count == 0 {
+		return ' '
+	}
+	return asciiShades[int(sum/float64(count)*float64(len(asciiShades)))]
+}
+
+// Returns the perceived brightness of c, from 0 up to but not including 1
+func brightness(c color.NRGBA) float64 {
+	return (0.299*float64(c.R) + 0.587*float64(c.G) + 0.114*float64(c.B)) / 256
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"internal/pokeapi"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// A 6x6 image with a transparent border around a white pixel over black beside a red one
+// The faint green pixel is too transparent to draw
+func testSprite() image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, 6, 6))
+	img.Set(1, 1, color.White)
+	img.Set(1, 2, color.Black)
+	img.Set(2, 2, color.NRGBA{R: 255, A: 255})
+	img.Set(4, 4, color.NRGBA{G: 255, A: 40})
+	return img
+}
+
+func TestWriteSpriteASCII(t *testing.T) {
+	var out bytes.Buffer
+	writeSprite(&out, testSprite(), plainStyle)
+	// White over black averages to a middle shade; red alone is darker
+	if want := "+#\n"; out.String() != want {
+		t.Errorf("ASCII sprite =\n%q\nwant\n%q", out.String(), want)
+	}
+}
+
+func TestWriteSpriteHalfBlocks(t *testing.T) {
+	var out bytes.Buffer
+	writeSprite(&out, testSprite(), textStyle{color: true, width: 80})
+	want := "\x1b[38;2;255;255;255;48;2;0;0;0m▀\x1b[0m\x1b[38;2;255;0;0m▄\x1b[0m\n"
+	if out.String() != want {
+		t.Errorf("half block sprite =\n%q\nwant\n%q", out.String(), want)
+	}
+}
+
+func TestWriteSpriteScalesToWidth(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 96, 8))
+	for x := 0; x < 96; x++ {
+		for y := 0; y < 8; y++ {
+			img.Set(x, y, color.White)
+		}
+	}
+	var out bytes.Buffer
+	writeSprite(&out, img, textStyle{width: 40})
+	// 96 pixels over 40 columns takes every third pixel, and every sixth row
+	lines := bytes.Split(bytes.TrimSuffix(out.Bytes(), []byte("\n")), []byte("\n"))
+	if len(lines) != 2 || len(lines[0]) != 32 {
+		t.Errorf("scaled sprite is %d lines of %d columns, want 2 of 32:\n%s", len(lines), len(lines[0]), out.String())
+	}
+}
+
+func TestInspectDrawsSpriteOnlyWhenAsked(t *testing.T) {
+	var out bytes.Buffer
+	r := newTestREPL(t, strings.NewReader(""), &out)
+	var sprite bytes.Buffer
+	if err := png.Encode(&sprite, testSprite()); err != nil {
+		t.Fatal(err)
+	}
+	api := fakeAPI{"sprites/pikachu.png": sprite.Bytes()}
+	r.api = &pokeapi.Client{HTTP: &http.Client{Transport: api}}
+	r.pokedex.Add("pikachu", json.RawMessage(`{"name":"pikachu","sprites":{"front_default":"`+pokeapi.BaseURL+`sprites/pikachu.png"},"types":[{"type":{"name":"electric"}}]}`))
+
+	// Output to a buffer is not a terminal, so the sprite is left out
+	if err := r.runLine("inspect pikachu"); err != nil {
+		t.Fatal(err)
+	}
+	if _, found := r.cache.Get(pokeapi.BaseURL + "sprites/pikachu.png"); found || strings.Contains(out.String(), "+#") {
+		t.Errorf("sprite fetched or drawn for output that is not a terminal:\n%s", out.String())
+	}
+	out.Reset()
+	if err := r.runLine("inspect --sprite pikachu"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "+#\n") {
+		t.Errorf("inspect --sprite did not start with the sprite:\n%s", out.String())
+	}
+}
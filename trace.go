@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"internal/pokeapi"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	registry.register(cliCommand{
+		name:        "trace",
+		category:    "Cache",
+		description: "Reports the API requests made by each command",
+		long: "While tracing, every resource a command fetches is listed after it on stderr with where it came from,\n" +
+			"its status, size and latency. With a file, each command's trace is also appended to it as a line of JSON.\n" +
+			"Tracing can also be turned on for a single run with \"pokedexcli --trace <command>\".",
+		examples: []string{"trace on", "trace on trace.jsonl", "trace off"},
+		callback: (*repl).traceStatus,
+		untraced: true,
+		subcommands: []cliCommand{
+			{name: "on", description: "Starts tracing commands", args: []argSpec{{name: "file", optional: true}}, callback: (*repl).traceOn},
+			{name: "off", description: "Stops tracing commands", callback: (*repl).traceOff},
+		},
+	})
+}
+
+// Trace of one command as written to the trace file
+type commandTrace struct {
+	Command  string               `json:"command"`
+	Start    time.Time            `json:"start"`
+	Duration time.Duration        `json:"duration_ns"`
+	Requests []pokeapi.TraceEvent `json:"requests"`
+}
+
+// Starts a trace of the command if tracing is on
+// Commands run by a traced command, such as those of a script, are part of its trace rather than traced on their own
+// Returns a function that stops it and reports the requests made to the session's errOut;
+// it does nothing if tracing is off or a trace was already in progress
+func (r *repl) startTrace(command string) func() {
+	if cfg.get("trace") != "on" {
+		return func() {}
+	}
+	start := time.Now()
+	if !r.api.StartTrace() {
+		return func() {}
+	}
+	return func() {
+		t := commandTrace{
+			Command:  command,
+			Start:    start,
+			Duration: time.Since(start),
+			Requests: r.api.StopTrace(),
+		}
+		if t.Requests == nil {
+			t.Requests = []pokeapi.TraceEvent{}
+		}
+		t.writeSummary(r.errOut, styleFor(r.errOut))
+		if path := cfg.get("trace_file"); path != "" {
+			if err := t.append(path); err != nil {
+				logger.Warn("trace file not written", "file", path, "err", err)
+			}
+		}
+	}
+}
+
+// Writes a line of totals followed by a table of the requests
+func (t commandTrace) writeSummary(w io.Writer, st textStyle) {
+	network, bytes := 0, 0
+	for _, e := range t.Requests {
+		if e.Source == "network" {
+			network++
+		}
+		bytes += e.Bytes
+	}
+	fmt.Fprintf(w, "%s %d fetched (%d over the network), %d bytes in %s\n",
+		st.paint("1", "trace:"), len(t.Requests), network, bytes, t.Duration.Round(time.Microsecond))
+	if len(t.Requests) == 0 {
+		return
+	}
+	requests := table{header: []string{"source", "status", "bytes", "latency", "url"}, right: []bool{false, true, true, true}}
+	for _, e := range t.Requests {
+		status := "-"
+		if e.Status != 0 {
+			status = strconv.Itoa(e.Status)
+		}
+		requests.rows = append(requests.rows, []string{
+			e.Source,
+			status,
+			strconv.Itoa(e.Bytes),
+			e.Latency.Round(time.Microsecond).String(),
+			strings.TrimPrefix(e.URL, pokeapi.BaseURL),
+		})
+	}
+	requests.write(w, st)
+}
+
+// Appends the trace to the file at path as a line of JSON
+func (t commandTrace) append(path string) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Prints whether tracing is on and the trace file, if any
+func (r *repl) traceStatus(arguments commandArgs) error {
+	if cfg.get("trace") != "on" {
+		fmt.Fprintln(r.out, "Tracing is off")
+		return nil
+	}
+	if path := cfg.get("trace_file"); path != "" {
+		fmt.Fprintf(r.out, "Tracing is on, appending to %s\n", path)
+	} else {
+		fmt.Fprintln(r.out, "Tracing is on")
+	}
+	return nil
+}
+
+// Turns tracing on for the rest of the session, appending to the file if one is given
+func (r *repl) traceOn(arguments commandArgs) error {
+	cfg.set("trace", "on", "session")
+	if file := arguments.Arg("file"); file != "" {
+		cfg.set("trace_file", file, "session")
+	}
+	return r.traceStatus(arguments)
+}
+
+// Turns tracing off for the rest of the session
+func (r *repl) traceOff(arguments commandArgs) error {
+	cfg.set("trace", "off", "session")
+	return r.traceStatus(arguments)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"internal/pokeapi"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTraceSummary(t *testing.T) {
+	trace := commandTrace{
+		Command:  "explore canalave-city-area",
+		Duration: 1500 * time.Microsecond,
+		Requests: []pokeapi.TraceEvent{
+			{URL: pokeapi.BaseURL + "location-area/canalave-city-area", Source: "network", Status: 200, Bytes: 2048, Latency: 1200 * time.Microsecond},
+			{URL: pokeapi.BaseURL + "pokemon/mew", Source: "memory", Bytes: 96, Latency: 3 * time.Microsecond},
+		},
+	}
+	var out bytes.Buffer
+	trace.writeSummary(&out, plainStyle)
+	want := "trace: 2 fetched (1 over the network), 2144 bytes in 1.5ms\n" +
+		"source   status  bytes  latency  url\n" +
+		"network     200   2048    1.2ms  location-area/canalave-city-area\n" +
+		"memory        -     96      3µs  pokemon/mew\n"
+	if out.String() != want {
+		t.Errorf("summary =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestTraceCommandAppendsToFile(t *testing.T) {
+	useConfigFile(t, "")
+	args, err := loadConfig([]string{"--trace", "help"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.get("trace") != "on" || !reflect.DeepEqual(args, []string{"help"}) {
+		t.Fatalf("--trace help left trace %q and args %q, want on and help", cfg.get("trace"), args)
+	}
+	path := filepath.Join(t.TempDir(), "trace.jsonl")
+	r := newREPL(strings.NewReader(""), io.Discard, io.Discard, nil, nil)
+	for _, line := range []string{"trace on " + path, "help exit", "trace off", "help exit"} {
+		if err := r.runLine(line); err != nil {
+			t.Fatalf("%s: %v", line, err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var commands []string
+	for _, line := range bytes.Split(bytes.TrimSpace(data), []byte("\n")) {
+		var trace commandTrace
+		if err := json.Unmarshal(line, &trace); err != nil {
+			t.Fatalf("trace line %q: %v", line, err)
+		}
+		if trace.Requests == nil || trace.Start.IsZero() {
+			t.Errorf("trace %+v has no requests list or start time", trace)
+		}
+		commands = append(commands, trace.Command)
+	}
+	// The trace command is never traced itself, and the last command runs untraced
+	if want := []string{"help exit"}; !reflect.DeepEqual(commands, want) {
+		t.Errorf("traced commands %q, want %q", commands, want)
+	}
+}
+
+func TestTraceCoversScriptCommands(t *testing.T) {
+	var out bytes.Buffer
+	r := newTestREPL(t, strings.NewReader("map\nexplore canalave-city-area\n"), &out)
+	cfg.set("trace", "on", "session")
+	if err := r.runLine("run -"); err != nil {
+		t.Fatal(err)
+	}
+	summaries := regexp.MustCompile(`trace: (\d+) fetched`).FindAllStringSubmatch(out.String(), -1)
+	if len(summaries) != 1 || summaries[0][1] == "0" {
+		t.Errorf("run - gave trace summaries %q, want one covering the script's requests:\n%s", summaries, out.String())
+	}
+}
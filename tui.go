@@ -0,0 +1,362 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"internal/lineedit"
+	"internal/pokeapi"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Panes that take the arrow keys
+const (
+	paneAreas = iota
+	paneEncounters
+)
+
+// Key help shown in the footer while there is no status to report
+const tuiHelp = "↑↓ move  ←→ switch pane  enter open  c catch  n/p next/previous page  q quit"
+
+// Full-screen browser of the location areas, the pokemon found in them and their details
+type tui struct {
+	// Where the screen is drawn, and the size used when it is not a terminal
+	out    io.Writer
+	width  int
+	height int
+
+	// Data sources, the session's pokeapi calls over its response cache and pokedex outside tests
+	mapPage func(dir int) ([]string, error)
+	explore func(area string) (pokeapi.AreaEncounters, error)
+	pokemon func(name string) (pokeapi.PokemonDetailedInformation, error)
+	catch   func(name string) (bool, error)
+	caught  func(name string) bool
+
+	page       int
+	areas      []string
+	area       int
+	encounters pokeapi.AreaEncounters
+	encounter  int
+	details    *inspectResult
+	// Whether the pokemon in details is in the pokedex
+	detailsCaught bool
+	focus         int
+	status        string
+}
+
+// Returns a browser drawing to the session's output, fetching through its response cache and catching into its pokedex
+func newTUI(r *repl) *tui {
+	return &tui{
+		out:    r.out,
+		width:  defaultWidth,
+		height: 24,
+		mapPage: func(dir int) ([]string, error) {
+			// Stop at the end rather than start the list over, so the page number stays right
+			if dir == 1 && r.api.LastPage() {
+				return nil, errors.New("Cannot retrieve next; at list end\n")
+			}
+			return r.api.GetAreaLocation(dir, r.cache)
+		},
+		explore: func(area string) (pokeapi.AreaEncounters, error) {
+			return r.api.Explore(area, r.cache)
+		},
+		pokemon: func(name string) (pokeapi.PokemonDetailedInformation, error) {
+			return r.api.Pokemon(name, r.cache)
+		},
+		catch: func(name string) (bool, error) {
+			_, known := r.pokedex.Get(name)
+			caught, err := r.api.Catch(name, r.rand, r.cache, r.pokedex)
+			if caught && !known {
+				r.unsaved.Add(1)
+			}
+			return caught, err
+		},
+		caught: func(name string) bool {
+			_, found := r.pokedex.Get(name)
+			return found
+		},
+	}
+}
+
+// Runs "pokedexcli tui", browsing until q is pressed
+// Returns the process exit code
+func runTUI(r *repl, args []string) int {
+	flags := flag.NewFlagSet("tui", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: pokedexcli tui")
+		fmt.Fprintln(flags.Output(), tuiHelp)
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return exitUsage
+	}
+	editor := lineedit.New(r.in, r.out)
+	restore, err := editor.Raw()
+	if err != nil {
+		fmt.Fprintln(r.errOut, "pokedexcli: tui needs a terminal")
+		return exitError
+	}
+	defer restore()
+	// Draw on the alternate screen with the cursor hidden, leaving the shell's screen as it was
+	fmt.Fprint(r.out, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(r.out, "\x1b[?25h\x1b[?1049l")
+
+	t := newTUI(r)
+	t.changePage(1)
+	for {
+		t.draw()
+		key, err := editor.ReadKey()
+		if err != nil || t.handle(key) {
+			return exitOK
+		}
+	}
+}
+
+// Acts on a key
+// Returns true when the key quits
+func (t *tui) handle(key string) bool {
+	switch key {
+	case "q", "ctrl-c", "ctrl-d":
+		return true
+	case "tab":
+		t.focus = 1 - t.focus
+	case "left", "h":
+		t.focus = paneAreas
+	case "right", "l":
+		t.focus = paneEncounters
+	case "up", "k":
+		t.move(-1)
+	case "down", "j":
+		t.move(1)
+	case "n", "pgdown":
+		t.changePage(1)
+	case "p", "pgup":
+		t.changePage(-1)
+	case "enter":
+		if t.focus == paneAreas {
+			t.exploreArea()
+		} else {
+			t.showPokemon()
+		}
+	case "c":
+		t.catchPokemon()
+	}
+	return false
+}
+
+// Moves the selection in the focused pane by delta, staying within the list
+func (t *tui) move(delta int) {
+	if t.focus == paneAreas {
+		t.area = min(max(t.area+delta, 0), max(len(t.areas)-1, 0))
+	} else {
+		t.encounter = min(max(t.encounter+delta, 0), max(len(t.encounters.Pokemon)-1, 0))
+	}
+}
+
+// Shows the next page of areas for a dir of 1, or the previous one for -1
+func (t *tui) changePage(dir int) {
+	t.busy("Loading areas...")
+	areas, err := t.mapPage(dir)
+	if err != nil {
+		t.fail(err)
+		return
+	}
+	t.areas, t.area, t.page = areas, 0, t.page+dir
+	t.focus = paneAreas
+	t.status = ""
+}
+
+// Lists the pokemon in the selected area
+func (t *tui) exploreArea() {
+	if len(t.areas) == 0 {
+		return
+	}
+	area := t.areas[t.area]
+	t.busy("Exploring " + area + "...")
+	encounters, err := t.explore(area)
+	if err != nil {
+		t.fail(err)
+		return
+	}
+	t.encounters, t.encounter = encounters, 0
+	t.focus = paneEncounters
+	t.status = fmt.Sprintf("%d pokemon in %s", len(encounters.Pokemon), encounters.DisplayName)
+}
+
+// Returns the selected pokemon, or "" before an area is explored
+func (t *tui) selectedPokemon() string {
+	if len(t.encounters.Pokemon) == 0 {
+		return ""
+	}
+	return t.encounters.Pokemon[t.encounter]
+}
+
+// Shows the details of the selected pokemon
+func (t *tui) showPokemon() {
+	name := t.selectedPokemon()
+	if name == "" {
+		return
+	}
+	t.busy("Looking up " + name + "...")
+	info, err := t.pokemon(name)
+	if err != nil {
+		t.fail(err)
+		return
+	}
+	details := newInspectResult(info)
+	t.details, t.detailsCaught = &details, t.caught(name)
+	t.status = ""
+}
+
+// Throws a pokeball at the selected pokemon
+func (t *tui) catchPokemon() {
+	name := t.selectedPokemon()
+	if name == "" {
+		t.status = "Explore an area and pick a pokemon to catch"
+		return
+	}
+	t.busy("Throwing a pokeball at " + name + "...")
+	caught, err := t.catch(name)
+	if err != nil {
+		t.fail(err)
+		return
+	}
+	var result strings.Builder
+	catchResult{Pokemon: name, Caught: caught}.writeText(&result, plainStyle)
+	t.status = strings.TrimSpace(result.String())
+	if caught && t.details != nil && t.details.Name == name {
+		t.detailsCaught = true
+	}
+}
+
+// Shows msg in the footer straight away, while a request runs
+func (t *tui) busy(msg string) {
+	t.status = msg
+	t.draw()
+}
+
+// Shows the error in the footer
+func (t *tui) fail(err error) {
+	t.status = "Error: " + strings.TrimSpace(errorText(err))
+}
+
+// Redraws the whole screen at the terminal's current size
+func (t *tui) draw() {
+	if f, ok := t.out.(*os.File); ok {
+		if width, height := lineedit.Width(int(f.Fd())), lineedit.Height(int(f.Fd())); width > 0 && height > 0 {
+			t.width, t.height = width, height
+		}
+	}
+	st := styleFor(t.out)
+	st.width = t.width
+	fmt.Fprint(t.out, "\x1b[H"+strings.Join(t.render(st), "\x1b[K\r\n")+"\x1b[K\x1b[J")
+}
+
+// Returns the lines of the screen: a title bar, the three panes side by side and a footer
+func (t *tui) render(st textStyle) []string {
+	const separator = " │ "
+	inner := max(t.width-2*len([]rune(separator)), 3)
+	areasWidth := inner * 3 / 10
+	encountersWidth := inner * 3 / 10
+	detailsWidth := inner - areasWidth - encountersWidth
+	rows := max(t.height-2, 1)
+
+	encountersTitle := "Pokemon"
+	if t.encounters.DisplayName != "" {
+		encountersTitle = "Pokemon in " + t.encounters.DisplayName
+	}
+	areas := t.listPane(st, "Areas, page "+strconv.Itoa(t.page), t.areas, t.area, t.focus == paneAreas, areasWidth, rows)
+	encounters := t.listPane(st, encountersTitle, t.encounters.Pokemon, t.encounter, t.focus == paneEncounters, encountersWidth, rows)
+	details := t.detailsPane(st, detailsWidth, rows)
+
+	lines := []string{st.paint("7", fit(" Pokedex", t.width))}
+	for i := 0; i < rows; i++ {
+		lines = append(lines, strings.TrimRight(areas[i]+separator+encounters[i]+separator+details[i], " "))
+	}
+	footer := t.status
+	if footer == "" {
+		footer = tuiHelp
+	}
+	return append(lines, st.paint("2", fit(footer, t.width)))
+}
+
+// Returns rows lines of width columns: a title, then the items scrolled to keep the selected one in view
+// The selected item is marked, and highlighted while the pane has the focus
+func (t *tui) listPane(st textStyle, title string, items []string, selected int, focused bool, width int, rows int) []string {
+	lines := []string{st.paint("1", fit(title, width))}
+	offset := max(0, selected-(rows-2))
+	for i := offset; i < len(items) && len(lines) < rows; i++ {
+		line := fit("  "+items[i], width)
+		if i == selected {
+			line = fit("> "+items[i], width)
+			if focused {
+				line = st.paint("7", line)
+			}
+		}
+		lines = append(lines, line)
+	}
+	return padLines(lines, width, rows)
+}
+
+// Returns rows lines of width columns describing the pokemon last looked up
+func (t *tui) detailsPane(st textStyle, width int, rows int) []string {
+	r := t.details
+	if r == nil {
+		return padLines([]string{fit("Press enter on a pokemon for details", width)}, width, rows)
+	}
+	title := fmt.Sprintf("%s #%d", r.Name, r.ID)
+	if t.detailsCaught {
+		title += " (caught)"
+	}
+	lines := []string{
+		st.paint("1", fit(title, width)),
+		fit("Types    "+st.typeNames(r.Types), width),
+		fit(fmt.Sprintf("Height   %d", r.Height), width),
+		fit(fmt.Sprintf("Weight   %d", r.Weight), width),
+		fit(fmt.Sprintf("Base XP  %d", r.BaseExperience), width),
+		fit("", width),
+	}
+	nameWidth := 0
+	for _, s := range r.Stats {
+		nameWidth = max(nameWidth, len(s.Name))
+	}
+	bar := min(width-nameWidth-5, maxStatBar)
+	for _, s := range r.Stats {
+		line := fmt.Sprintf("%-*s %3d", nameWidth, s.Name, s.Base)
+		if bar >= minStatBar {
+			line += " " + st.statBar(s.Base, bar)
+		}
+		lines = append(lines, fit(line, width))
+	}
+	return padLines(lines, width, rows)
+}
+
+// Cuts lines to rows and adds blank lines of width columns to make up rows
+func padLines(lines []string, width int, rows int) []string {
+	if len(lines) > rows {
+		return lines[:rows]
+	}
+	for len(lines) < rows {
+		lines = append(lines, strings.Repeat(" ", width))
+	}
+	return lines
+}
+
+// Pads s with spaces to width columns, or cuts it to width ending in "…"
+// Colors are dropped from text that has to be cut
+func fit(s string, width int) string {
+	if w := textWidth(s); w <= width {
+		return s + strings.Repeat(" ", width-w)
+	}
+	runes := []rune(ansiEscape.ReplaceAllString(s, ""))
+	if width < 1 {
+		return ""
+	}
+	return string(runes[:width-1]) + "…"
+}
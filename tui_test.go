@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"internal/pokeapi"
+	"io"
+	"strings"
+	"testing"
+)
+
+// Returns a browser over two pages of areas whose pokemon are caught on the second throw
+func testTUI() *tui {
+	pages := [][]string{{"canalave-city-area", "eterna-city-area"}, {"pastoria-city-area"}}
+	page := -1
+	caught := map[string]bool{}
+	throws := 0
+	return &tui{
+		out:    io.Discard,
+		width:  90,
+		height: 12,
+		mapPage: func(dir int) ([]string, error) {
+			if page+dir < 0 || page+dir >= len(pages) {
+				return nil, errors.New("no more areas\n")
+			}
+			page += dir
+			return pages[page], nil
+		},
+		explore: func(area string) (pokeapi.AreaEncounters, error) {
+			return pokeapi.AreaEncounters{Name: area, DisplayName: area, Pokemon: []string{"tentacool", "staryu"}}, nil
+		},
+		pokemon: func(name string) (pokeapi.PokemonDetailedInformation, error) {
+			return pokeapi.PokemonDetailedInformation{Name: name, Id: 72}, nil
+		},
+		catch: func(name string) (bool, error) {
+			throws++
+			caught[name] = throws%2 == 0
+			return caught[name], nil
+		},
+		caught: func(name string) bool { return caught[name] },
+	}
+}
+
+func press(t *tui, keys ...string) {
+	for _, key := range keys {
+		t.handle(key)
+	}
+}
+
+func TestTUINavigation(t *testing.T) {
+	ui := testTUI()
+	press(ui, "n", "down", "down", "enter")
+	if ui.encounters.Name != "eterna-city-area" || ui.focus != paneEncounters {
+		t.Fatalf("explored %q with focus %d, want eterna-city-area in the encounters pane", ui.encounters.Name, ui.focus)
+	}
+	press(ui, "j", "enter")
+	if ui.details == nil || ui.details.Name != "staryu" || ui.detailsCaught {
+		t.Fatalf("details = %+v, want uncaught staryu", ui.details)
+	}
+	press(ui, "c")
+	if ui.detailsCaught || !strings.Contains(ui.status, "escaped") {
+		t.Errorf("after a missed throw caught = %v, status %q", ui.detailsCaught, ui.status)
+	}
+	press(ui, "c")
+	if !ui.detailsCaught || !strings.Contains(ui.status, "captured") {
+		t.Errorf("after a good throw caught = %v, status %q", ui.detailsCaught, ui.status)
+	}
+	press(ui, "n")
+	if ui.page != 2 || ui.area != 0 || ui.focus != paneAreas {
+		t.Errorf("next page is page %d, area %d, focus %d; want 2, 0, %d", ui.page, ui.area, ui.focus, paneAreas)
+	}
+	press(ui, "n")
+	if ui.page != 2 || !strings.HasPrefix(ui.status, "Error: no more areas") {
+		t.Errorf("past the last page: page %d, status %q", ui.page, ui.status)
+	}
+	if !ui.handle("q") {
+		t.Error("q does not quit")
+	}
+}
+
+func TestTUIStopsAtTheLastPage(t *testing.T) {
+	ui := newTUI(newTestREPL(t, strings.NewReader(""), io.Discard))
+	press(ui, "n", "n")
+	if ui.page != 2 || ui.areas[0] != "mt-coronet-1f" {
+		t.Fatalf("second page is page %d with %q", ui.page, ui.areas)
+	}
+	press(ui, "n")
+	if ui.page != 2 || ui.areas[0] != "mt-coronet-1f" || !strings.Contains(ui.status, "at list end") {
+		t.Errorf("past the last page: page %d with %q, status %q", ui.page, ui.areas, ui.status)
+	}
+	press(ui, "p")
+	if ui.page != 1 || ui.areas[0] != "canalave-city-area" {
+		t.Errorf("back from the last page: page %d with %q", ui.page, ui.areas)
+	}
+}
+
+func TestTUIRender(t *testing.T) {
+	ui := testTUI()
+	press(ui, "n", "enter", "right", "enter")
+	lines := ui.render(plainStyle)
+	if len(lines) != ui.height {
+		t.Fatalf("rendered %d lines, want %d", len(lines), ui.height)
+	}
+	for i, line := range lines {
+		if w := textWidth(line); w > ui.width {
+			t.Errorf("line %d is %d columns wide, more than %d: %q", i, w, ui.width, line)
+		}
+	}
+	for _, want := range []string{"Areas, page 1", "> canalave-city-area", "> tentacool", "tentacool #72"} {
+		if !strings.Contains(strings.Join(lines, "\n"), want) {
+			t.Errorf("screen does not show %q:\n%s", want, strings.Join(lines, "\n"))
+		}
+	}
+	if footer := lines[len(lines)-1]; !strings.HasPrefix(footer, "↑↓ move") {
+		t.Errorf("footer = %q, want the key help", footer)
+	}
+}
+
+func TestFit(t *testing.T) {
+	for _, tt := range []struct {
+		in    string
+		width int
+		want  string
+	}{
+		{"abc", 5, "abc  "},
+		{"abcdef", 4, "abc…"},
+		{"\x1b[1mab\x1b[0m", 3, "\x1b[1mab\x1b[0m "},
+		{"\x1b[1mabcd\x1b[0m", 3, "ab…"},
+	} {
+		if got := fit(tt.in, tt.width); got != tt.want {
+			t.Errorf("fit(%q, %d) = %q, want %q", tt.in, tt.width, got, tt.want)
+		}
+	}
+}
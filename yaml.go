@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Value decoded from JSON with object keys kept in order
+type yamlNode struct {
+	keys   []string
+	fields []*yamlNode
+	items  []*yamlNode
+	// Set for scalars: the YAML text of a string, number, bool or null
+	scalar string
+	kind   byte
+}
+
+// Writes v as a YAML document, starting with "---" so several can share a stream
+// v is encoded through its JSON form, so field names and order match the JSON output
+func writeYAML(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	node, err := decodeYAMLNode(dec)
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	b.WriteString("---\n")
+	switch {
+	case node.kind == '{' && len(node.keys) > 0, node.kind == '[' && len(node.items) > 0:
+		node.write(&b, 0)
+	default:
+		b.WriteString(node.inline() + "\n")
+	}
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// Reads the next JSON value from dec
+func decodeYAMLNode(dec *json.Decoder) (*yamlNode, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		node := &yamlNode{kind: byte(t)}
+		for dec.More() {
+			if t == '{' {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				node.keys = append(node.keys, key.(string))
+			}
+			child, err := decodeYAMLNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			if t == '{' {
+				node.fields = append(node.fields, child)
+			} else {
+				node.items = append(node.items, child)
+			}
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return node, nil
+	case string:
+		return &yamlNode{scalar: yamlString(t)}, nil
+	case json.Number:
+		return &yamlNode{scalar: t.String()}, nil
+	case bool:
+		return &yamlNode{scalar: strconv.FormatBool(t)}, nil
+	default:
+		return &yamlNode{scalar: "null"}, nil
+	}
+}
+
+// Reports whether the node is written on the same line as its key or dash
+func (n *yamlNode) isInline() bool {
+	return n.kind == 0 || (n.kind == '{' && len(n.keys) == 0) || (n.kind == '[' && len(n.items) == 0)
+}
+
+// Returns the YAML text of a scalar or empty collection
+func (n *yamlNode) inline() string {
+	switch n.kind {
+	case '{':
+		return "{}"
+	case '[':
+		return "[]"
+	default:
+		return n.scalar
+	}
+}
+
+// Writes an object or array in block style at the given indent
+func (n *yamlNode) write(b *strings.Builder, indent int) {
+	pad := strings.Repeat("  ", indent)
+	if n.kind == '{' {
+		for i, key := range n.keys {
+			n.writeEntry(b, pad+yamlString(key)+":", n.fields[i], indent)
+		}
+		return
+	}
+	for _, item := range n.items {
+		if item.kind == '{' && len(item.keys) > 0 {
+			// The first field goes on the dash line, the rest line up under it
+			var inner strings.Builder
+			item.write(&inner, indent+1)
+			b.WriteString(pad + "- " + strings.TrimPrefix(inner.String(), pad+"  "))
+			continue
+		}
+		n.writeEntry(b, pad+"-", item, indent)
+	}
+}
+
+// Writes a key or dash followed by its value
+func (n *yamlNode) writeEntry(b *strings.Builder, lead string, value *yamlNode, indent int) {
+	if value.isInline() {
+		fmt.Fprintf(b, "%s %s\n", lead, value.inline())
+		return
+	}
+	b.WriteString(lead + "\n")
+	value.write(b, indent+1)
+}
+
+// Strings that YAML would read as something other than a plain string
+var yamlPlain = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_ ./-]*$`)
+
+var yamlReserved = map[string]bool{
+	"true": true, "false": true, "yes": true, "no": true, "on": true, "off": true,
+	"null": true, "y": true, "n": true,
+}
+
+// Returns s unquoted when YAML reads it back unchanged, and double quoted otherwise
+func yamlString(s string) string {
+	if yamlPlain.MatchString(s) && !strings.HasSuffix(s, " ") && !yamlReserved[strings.ToLower(s)] {
+		return s
+	}
+	return strconv.Quote(s)
+}